@@ -0,0 +1,482 @@
+package main
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+type VdatAuth struct {
+	Type     string `json:"Type"`
+	Username string `json:"Username"`
+	Password string `json:"Password"`
+	Token    string `json:"Token"`
+	Key      string `json:"Key"`
+	Value    string `json:"Value"`
+	In       string `json:"In"`
+
+	Grant        string `json:"Grant"`
+	TokenUrl     string `json:"TokenUrl"`
+	AuthUrl      string `json:"AuthUrl"`
+	ClientId     string `json:"ClientId"`
+	ClientSecret string `json:"ClientSecret"`
+	Scope        string `json:"Scope"`
+	RefreshToken string `json:"RefreshToken"`
+
+	Region       string `json:"Region"`
+	Service      string `json:"Service"`
+	AccessKey    string `json:"AccessKey"`
+	SecretKey    string `json:"SecretKey"`
+	SessionToken string `json:"SessionToken"`
+
+	HmacKey       string `json:"HmacKey"`
+	HmacAlgorithm string `json:"HmacAlgorithm"`
+	HmacHeader    string `json:"HmacHeader"`
+	HmacSigns     string `json:"HmacSigns"`
+	HmacEncoding  string `json:"HmacEncoding"`
+}
+
+func (auth VdatAuth) authorization() string {
+	switch auth.Type {
+	case AUTH_TYPE_BASIC:
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(auth.Username+":"+auth.Password))
+	case AUTH_TYPE_BEARER, AUTH_TYPE_OAUTH2:
+		return "Bearer " + auth.Token
+	}
+	return ""
+}
+
+func addAuthQuery(urlText string, auth VdatAuth) (string, error) {
+	if auth.Type != AUTH_TYPE_API_KEY || auth.In != API_KEY_IN_QUERY {
+		return urlText, nil
+	}
+	if auth.Key == "" {
+		return "", errors.New("Missing API key name")
+	}
+	parsedUrl, err := url.Parse(urlText)
+	if err != nil {
+		return "", err
+	}
+	query := url.QueryEscape(decodePercent(auth.Key)) + "=" + url.QueryEscape(decodePercent(auth.Value))
+	if parsedUrl.RawQuery != "" {
+		parsedUrl.RawQuery += "&" + query
+	} else {
+		parsedUrl.RawQuery = query
+	}
+	return parsedUrl.String(), nil
+}
+
+// applyAuth sets the headers for the auth scheme. Headers typed by hand win,
+// the same as for Content-Type. Digest needs a challenge first so it is
+// answered in sendVdatRequest.
+func applyAuth(req *http.Request, vdatRequest VdatRequest) error {
+	auth := vdatRequest.Auth
+	switch auth.Type {
+	case AUTH_TYPE_BASIC, AUTH_TYPE_BEARER, AUTH_TYPE_OAUTH2:
+		if !hasHeader(vdatRequest.Headers, "Authorization") {
+			req.Header.Set("Authorization", auth.authorization())
+		}
+	case AUTH_TYPE_API_KEY:
+		if auth.In == API_KEY_IN_QUERY {
+			return nil
+		}
+		key := strings.TrimSpace(auth.Key)
+		if !validHeaderName(key) || !validHeaderValue(auth.Value) {
+			return errors.New(fmt.Sprint("Error with API key: ", key, "=", auth.Value))
+		}
+		if !hasHeader(vdatRequest.Headers, key) {
+			req.Header.Set(key, auth.Value)
+		}
+	}
+	return nil
+}
+
+func impliedAuthHeader(vdatRequest VdatRequest) (string, string) {
+	auth := vdatRequest.Auth
+	switch auth.Type {
+	case AUTH_TYPE_BASIC:
+		return "Authorization", "Basic <credentials>"
+	case AUTH_TYPE_BEARER:
+		return "Authorization", "Bearer <token>"
+	case AUTH_TYPE_DIGEST:
+		return "Authorization", "Digest <answer to challenge>"
+	case AUTH_TYPE_OAUTH2:
+		return "Authorization", "Bearer <oauth 2.0 token>"
+	case AUTH_TYPE_AWS_SIGV4:
+		return "Authorization", "AWS4-HMAC-SHA256 <signature>"
+	case AUTH_TYPE_HMAC:
+		return hmacHeaderName(auth), "<signature>"
+	case AUTH_TYPE_API_KEY:
+		if auth.In != API_KEY_IN_QUERY && auth.Key != "" {
+			return strings.TrimSpace(auth.Key), "<api key>"
+		}
+	}
+	return "", ""
+}
+
+// findDigestChallenge returns the parameters of the first Digest challenge
+// in the WWW-Authenticate headers of a response.
+func findDigestChallenge(resp *http.Response) (map[string]string, bool) {
+	for _, value := range resp.Header.Values("WWW-Authenticate") {
+		scheme, params, _ := strings.Cut(strings.TrimSpace(value), " ")
+		if strings.EqualFold(scheme, "Digest") {
+			return parseAuthParams(params), true
+		}
+	}
+	return nil, false
+}
+
+func parseAuthParams(text string) map[string]string {
+	params := make(map[string]string)
+	for {
+		text = strings.TrimLeft(text, " \t,")
+		if text == "" {
+			return params
+		}
+		key, rest, found := strings.Cut(text, "=")
+		if !found {
+			return params
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		rest = strings.TrimLeft(rest, " \t")
+		var value strings.Builder
+		if strings.HasPrefix(rest, "\"") {
+			index := 1
+			for ; index < len(rest) && rest[index] != '"'; index++ {
+				if rest[index] == '\\' && index+1 < len(rest) {
+					index++
+				}
+				value.WriteByte(rest[index])
+			}
+			text = rest[min(index+1, len(rest)):]
+		} else {
+			end := strings.IndexByte(rest, ',')
+			if end < 0 {
+				end = len(rest)
+			}
+			value.WriteString(strings.TrimSpace(rest[:end]))
+			text = rest[end:]
+		}
+		params[key] = value.String()
+	}
+}
+
+func digestAuthorization(challenge map[string]string, auth VdatAuth, method string, uri string) (string, error) {
+	algorithm := challenge["algorithm"]
+	if algorithm == "" {
+		algorithm = "MD5"
+	}
+	var newHash func() hash.Hash
+	switch strings.TrimSuffix(strings.ToUpper(algorithm), "-SESS") {
+	case "MD5":
+		newHash = md5.New
+	case "SHA-256":
+		newHash = sha256.New
+	default:
+		return "", errors.New(fmt.Sprint("Unsupported digest algorithm: ", algorithm))
+	}
+	digest := func(parts ...string) string {
+		h := newHash()
+		h.Write([]byte(strings.Join(parts, ":")))
+		return hex.EncodeToString(h.Sum(nil))
+	}
+
+	qop := ""
+	if challenge["qop"] != "" {
+		for _, option := range strings.Split(challenge["qop"], ",") {
+			if strings.TrimSpace(option) == "auth" {
+				qop = "auth"
+			}
+		}
+		if qop == "" {
+			return "", errors.New(fmt.Sprint("Unsupported digest qop: ", challenge["qop"]))
+		}
+	}
+	cnonceBytes := make([]byte, 16)
+	_, err := rand.Read(cnonceBytes)
+	if err != nil {
+		return "", err
+	}
+	cnonce := hex.EncodeToString(cnonceBytes)
+	nc := "00000001"
+
+	ha1 := digest(auth.Username, challenge["realm"], auth.Password)
+	if strings.HasSuffix(strings.ToUpper(algorithm), "-SESS") {
+		ha1 = digest(ha1, challenge["nonce"], cnonce)
+	}
+	ha2 := digest(method, uri)
+	var response string
+	if qop != "" {
+		response = digest(ha1, challenge["nonce"], nc, cnonce, qop, ha2)
+	} else {
+		response = digest(ha1, challenge["nonce"], ha2)
+	}
+
+	quote := strings.NewReplacer("\\", "\\\\", "\"", "\\\"").Replace
+	parts := []string{
+		fmt.Sprint("username=\"", quote(auth.Username), "\""),
+		fmt.Sprint("realm=\"", quote(challenge["realm"]), "\""),
+		fmt.Sprint("nonce=\"", quote(challenge["nonce"]), "\""),
+		fmt.Sprint("uri=\"", quote(uri), "\""),
+		fmt.Sprint("algorithm=", algorithm),
+		fmt.Sprint("response=\"", response, "\""),
+	}
+	if qop != "" {
+		parts = append(parts, "qop="+qop, "nc="+nc, fmt.Sprint("cnonce=\"", cnonce, "\""))
+	}
+	if opaque, found := challenge["opaque"]; found {
+		parts = append(parts, fmt.Sprint("opaque=\"", quote(opaque), "\""))
+	}
+	return "Digest " + strings.Join(parts, ", "), nil
+}
+
+func newAuthEntries(onChanged func(), onClearToken func(VdatAuth)) (fyne.CanvasObject, func() VdatAuth, func(VdatAuth)) {
+	changed := func(string) {
+		if onChanged != nil {
+			onChanged()
+		}
+	}
+	username := widget.NewEntry()
+	username.OnChanged = changed
+	password := widget.NewPasswordEntry()
+	password.OnChanged = changed
+	token := widget.NewEntry()
+	token.OnChanged = changed
+	key := widget.NewEntry()
+	key.OnChanged = changed
+	value := widget.NewEntry()
+	value.OnChanged = changed
+	in := widget.NewSelect(API_KEY_LOCATIONS, changed)
+	in.SetSelected(API_KEY_IN_HEADER)
+	tokenUrl := widget.NewEntry()
+	tokenUrl.SetPlaceHolder(URL_PLACEHOLDER)
+	tokenUrl.OnChanged = changed
+	authUrl := widget.NewEntry()
+	authUrl.SetPlaceHolder(URL_PLACEHOLDER)
+	authUrl.OnChanged = changed
+	clientId := widget.NewEntry()
+	clientId.OnChanged = changed
+	clientSecret := widget.NewPasswordEntry()
+	clientSecret.OnChanged = changed
+	scope := widget.NewEntry()
+	scope.OnChanged = changed
+	refreshToken := widget.NewEntry()
+	refreshToken.OnChanged = changed
+	region := widget.NewEntry()
+	region.OnChanged = changed
+	service := widget.NewEntry()
+	service.OnChanged = changed
+	accessKey := widget.NewEntry()
+	accessKey.OnChanged = changed
+	secretKey := widget.NewPasswordEntry()
+	secretKey.OnChanged = changed
+	sessionToken := widget.NewPasswordEntry()
+	sessionToken.OnChanged = changed
+	hmacKey := widget.NewPasswordEntry()
+	hmacKey.OnChanged = changed
+	hmacAlgorithm := widget.NewSelect(HMAC_ALGORITHMS, changed)
+	hmacAlgorithm.SetSelected(HMAC_ALGORITHM_SHA256)
+	hmacHeader := widget.NewEntry()
+	hmacHeader.SetPlaceHolder(HMAC_DEFAULT_HEADER)
+	hmacHeader.OnChanged = changed
+	hmacSigns := widget.NewSelect(HMAC_SIGNS, changed)
+	hmacSigns.SetSelected(HMAC_SIGNS_BODY)
+	hmacEncoding := widget.NewSelect(HMAC_ENCODINGS, changed)
+	hmacEncoding.SetSelected(HMAC_ENCODING_HEX)
+
+	credentialsForm := widget.NewForm(
+		widget.NewFormItem(USERNAME_TEXT, username),
+		widget.NewFormItem(PASSWORD_TEXT, password))
+	tokenForm := widget.NewForm(widget.NewFormItem(TOKEN_TEXT, token))
+	apiKeyForm := widget.NewForm(
+		widget.NewFormItem(API_KEY_NAME_TEXT, key),
+		widget.NewFormItem(API_KEY_VALUE_TEXT, value),
+		widget.NewFormItem(API_KEY_IN_TEXT, in))
+	authUrlForm := widget.NewForm(widget.NewFormItem(AUTH_URL_TEXT, authUrl))
+	refreshTokenForm := widget.NewForm(widget.NewFormItem(REFRESH_TOKEN_TEXT, refreshToken))
+	awsForm := widget.NewForm(
+		widget.NewFormItem(REGION_TEXT, region),
+		widget.NewFormItem(SERVICE_TEXT, service),
+		widget.NewFormItem(ACCESS_KEY_TEXT, accessKey),
+		widget.NewFormItem(SECRET_KEY_TEXT, secretKey),
+		widget.NewFormItem(SESSION_TOKEN_TEXT, sessionToken))
+	hmacForm := widget.NewForm(
+		widget.NewFormItem(HMAC_KEY_TEXT, hmacKey),
+		widget.NewFormItem(HMAC_ALGORITHM_TEXT, hmacAlgorithm),
+		widget.NewFormItem(HMAC_HEADER_TEXT, hmacHeader),
+		widget.NewFormItem(HMAC_SIGNS_TEXT, hmacSigns),
+		widget.NewFormItem(HMAC_ENCODING_TEXT, hmacEncoding))
+
+	var authType *widget.Select
+	var grant *widget.Select
+	getAuth := func() VdatAuth {
+		auth := VdatAuth{Type: authType.Selected}
+		switch authType.Selected {
+		case AUTH_TYPE_BASIC, AUTH_TYPE_DIGEST:
+			auth.Username = username.Text
+			auth.Password = password.Text
+		case AUTH_TYPE_BEARER:
+			auth.Token = token.Text
+		case AUTH_TYPE_API_KEY:
+			auth.Key = key.Text
+			auth.Value = value.Text
+			auth.In = in.Selected
+		case AUTH_TYPE_OAUTH2:
+			auth.Grant = grant.Selected
+			auth.TokenUrl = tokenUrl.Text
+			auth.ClientId = clientId.Text
+			auth.ClientSecret = clientSecret.Text
+			auth.Scope = scope.Text
+			switch grant.Selected {
+			case OAUTH2_GRANT_PASSWORD:
+				auth.Username = username.Text
+				auth.Password = password.Text
+			case OAUTH2_GRANT_REFRESH_TOKEN:
+				auth.RefreshToken = refreshToken.Text
+			case OAUTH2_GRANT_AUTHORIZATION_CODE:
+				auth.AuthUrl = authUrl.Text
+			}
+		case AUTH_TYPE_AWS_SIGV4:
+			auth.Region = region.Text
+			auth.Service = service.Text
+			auth.AccessKey = accessKey.Text
+			auth.SecretKey = secretKey.Text
+			auth.SessionToken = sessionToken.Text
+		case AUTH_TYPE_HMAC:
+			auth.HmacKey = hmacKey.Text
+			auth.HmacAlgorithm = hmacAlgorithm.Selected
+			auth.HmacHeader = hmacHeader.Text
+			auth.HmacSigns = hmacSigns.Selected
+			auth.HmacEncoding = hmacEncoding.Selected
+		}
+		return auth
+	}
+	clearTokenButton := widget.NewButton(CLEAR_TOKEN_BUTTON_TEXT, func() {
+		if onClearToken != nil {
+			onClearToken(getAuth())
+		}
+	})
+	oauth2Form := widget.NewForm(
+		widget.NewFormItem(TOKEN_URL_TEXT, tokenUrl),
+		widget.NewFormItem(CLIENT_ID_TEXT, clientId),
+		widget.NewFormItem(CLIENT_SECRET_TEXT, clientSecret),
+		widget.NewFormItem(SCOPE_TEXT, scope))
+	oauth2Controls := container.NewVBox(oauth2Form, container.NewHBox(clearTokenButton))
+
+	showForms := func() {
+		credentialsForm.Hide()
+		tokenForm.Hide()
+		apiKeyForm.Hide()
+		oauth2Controls.Hide()
+		authUrlForm.Hide()
+		refreshTokenForm.Hide()
+		awsForm.Hide()
+		hmacForm.Hide()
+		grant.Hide()
+		switch authType.Selected {
+		case AUTH_TYPE_BASIC, AUTH_TYPE_DIGEST:
+			credentialsForm.Show()
+		case AUTH_TYPE_BEARER:
+			tokenForm.Show()
+		case AUTH_TYPE_API_KEY:
+			apiKeyForm.Show()
+		case AUTH_TYPE_OAUTH2:
+			grant.Show()
+			oauth2Controls.Show()
+			switch grant.Selected {
+			case OAUTH2_GRANT_PASSWORD:
+				credentialsForm.Show()
+			case OAUTH2_GRANT_REFRESH_TOKEN:
+				refreshTokenForm.Show()
+			case OAUTH2_GRANT_AUTHORIZATION_CODE:
+				authUrlForm.Show()
+			}
+		case AUTH_TYPE_AWS_SIGV4:
+			awsForm.Show()
+		case AUTH_TYPE_HMAC:
+			hmacForm.Show()
+		}
+	}
+	grant = widget.NewSelect(OAUTH2_GRANTS, func(selected string) {
+		if authType != nil {
+			showForms()
+		}
+		changed(selected)
+	})
+	grant.SetSelected(OAUTH2_GRANT_CLIENT_CREDENTIALS)
+	authType = widget.NewSelect(AUTH_TYPES, func(selected string) {
+		showForms()
+		changed(selected)
+	})
+	authType.SetSelected(AUTH_TYPE_NONE)
+
+	content := container.NewVBox(
+		widget.NewForm(
+			widget.NewFormItem(AUTH_TYPE_TEXT, authType),
+			widget.NewFormItem("", grant)),
+		credentialsForm,
+		tokenForm,
+		apiKeyForm,
+		authUrlForm,
+		refreshTokenForm,
+		oauth2Controls,
+		awsForm,
+		hmacForm)
+	setAuth := func(auth VdatAuth) {
+		username.SetText(auth.Username)
+		password.SetText(auth.Password)
+		token.SetText(auth.Token)
+		key.SetText(auth.Key)
+		value.SetText(auth.Value)
+		if auth.In == "" {
+			auth.In = API_KEY_IN_HEADER
+		}
+		in.SetSelected(auth.In)
+		tokenUrl.SetText(auth.TokenUrl)
+		authUrl.SetText(auth.AuthUrl)
+		clientId.SetText(auth.ClientId)
+		clientSecret.SetText(auth.ClientSecret)
+		scope.SetText(auth.Scope)
+		refreshToken.SetText(auth.RefreshToken)
+		region.SetText(auth.Region)
+		service.SetText(auth.Service)
+		accessKey.SetText(auth.AccessKey)
+		secretKey.SetText(auth.SecretKey)
+		sessionToken.SetText(auth.SessionToken)
+		hmacKey.SetText(auth.HmacKey)
+		hmacHeader.SetText(auth.HmacHeader)
+		if auth.HmacAlgorithm == "" {
+			auth.HmacAlgorithm = HMAC_ALGORITHM_SHA256
+		}
+		hmacAlgorithm.SetSelected(auth.HmacAlgorithm)
+		if auth.HmacSigns == "" {
+			auth.HmacSigns = HMAC_SIGNS_BODY
+		}
+		hmacSigns.SetSelected(auth.HmacSigns)
+		if auth.HmacEncoding == "" {
+			auth.HmacEncoding = HMAC_ENCODING_HEX
+		}
+		hmacEncoding.SetSelected(auth.HmacEncoding)
+		if auth.Grant == "" {
+			auth.Grant = OAUTH2_GRANT_CLIENT_CREDENTIALS
+		}
+		grant.SetSelected(auth.Grant)
+		if auth.Type == "" {
+			auth.Type = AUTH_TYPE_NONE
+		}
+		authType.SetSelected(auth.Type)
+	}
+	return container.NewVScroll(content), getAuth, setAuth
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/yosssi/gohtml"
+)
+
+func rawContentType(language string) string {
+	switch language {
+	case RAW_LANGUAGE_JSON:
+		return "application/json"
+	case RAW_LANGUAGE_XML:
+		return "application/xml"
+	case RAW_LANGUAGE_HTML:
+		return "text/html; charset=utf-8"
+	case RAW_LANGUAGE_TEXT:
+		return "text/plain; charset=utf-8"
+	case RAW_LANGUAGE_NDJSON:
+		return "application/x-ndjson"
+	}
+	return ""
+}
+
+func beautify(content string, language string) (string, error) {
+	switch language {
+	case RAW_LANGUAGE_JSON:
+		var buffer bytes.Buffer
+		err := json.Indent(&buffer, []byte(content), "", "  ")
+		if err != nil {
+			return content, errors.New(fmt.Sprint("Invalid JSON: ", err))
+		}
+		return buffer.String(), nil
+	case RAW_LANGUAGE_NDJSON:
+		lines := []string{}
+		for number, line := range strings.Split(content, "\n") {
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			var buffer bytes.Buffer
+			err := json.Compact(&buffer, []byte(line))
+			if err != nil {
+				return content, errors.New(fmt.Sprint("Invalid JSON on line ", number+1, ": ", err))
+			}
+			lines = append(lines, buffer.String())
+		}
+		return strings.Join(lines, "\n"), nil
+	case RAW_LANGUAGE_XML:
+		return beautifyXml(content)
+	case RAW_LANGUAGE_HTML:
+		return gohtml.Format(content), nil
+	case RAW_LANGUAGE_TEXT:
+		return content, nil
+	}
+	return smartFormat([]byte(content)), nil
+}
+
+func xmlName(name xml.Name) string {
+	if name.Space != "" {
+		return name.Space + ":" + name.Local
+	}
+	return name.Local
+}
+
+func beautifyXml(content string) (string, error) {
+	var buffer bytes.Buffer
+	decoder := xml.NewDecoder(strings.NewReader(content))
+	depth := 0
+	var previous xml.Token
+	newLine := func() {
+		if buffer.Len() != 0 {
+			buffer.WriteString("\n")
+		}
+		buffer.WriteString(strings.Repeat("  ", depth))
+	}
+	for {
+		// raw tokens keep namespace prefixes as written
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return content, errors.New(fmt.Sprint("Invalid XML: ", err))
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			newLine()
+			buffer.WriteString("<" + xmlName(token.Name))
+			for _, attr := range token.Attr {
+				buffer.WriteString(" " + xmlName(attr.Name) + "=\"")
+				xml.EscapeText(&buffer, []byte(attr.Value))
+				buffer.WriteString("\"")
+			}
+			buffer.WriteString(">")
+			depth++
+		case xml.EndElement:
+			depth--
+			switch previous.(type) {
+			case xml.StartElement, xml.CharData:
+			default:
+				newLine()
+			}
+			buffer.WriteString("</" + xmlName(token.Name) + ">")
+		case xml.CharData:
+			text := bytes.TrimSpace(token)
+			if len(text) == 0 {
+				continue
+			}
+			xml.EscapeText(&buffer, text)
+		case xml.Comment:
+			newLine()
+			buffer.WriteString("<!--" + string(token) + "-->")
+		case xml.ProcInst:
+			newLine()
+			buffer.WriteString("<?" + token.Target + " " + string(token.Inst) + "?>")
+		case xml.Directive:
+			newLine()
+			buffer.WriteString("<!" + string(token) + ">")
+		}
+		previous = xml.CopyToken(token)
+	}
+	if depth != 0 {
+		return content, errors.New("Invalid XML: unclosed elements")
+	}
+	return buffer.String(), nil
+}
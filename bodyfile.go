@@ -0,0 +1,82 @@
+package main
+
+import (
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// bodyFileDir is where relative body file paths start: the top-level
+// collection folder of a request saved in the vdat folder, the folder of a
+// request saved elsewhere, or the vdat folder for an unsaved request.
+func bodyFileDir(requestPath string) (string, error) {
+	vdatDir, err := getVdatDir()
+	if err != nil || requestPath == "" {
+		return vdatDir, err
+	}
+	relativePath, err := filepath.Rel(vdatDir, requestPath)
+	if err != nil || relativePath == ".." || strings.HasPrefix(relativePath, ".."+string(filepath.Separator)) {
+		return filepath.Dir(requestPath), nil
+	}
+	collection, _, found := strings.Cut(relativePath, string(filepath.Separator))
+	if !found {
+		return vdatDir, nil
+	}
+	return filepath.Join(vdatDir, collection), nil
+}
+
+func resolveBodyFilePath(path string, requestPath string) (string, error) {
+	if strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(home, path[2:]), nil
+	}
+	if filepath.IsAbs(path) {
+		return path, nil
+	}
+	dir, err := bodyFileDir(requestPath)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, path), nil
+}
+
+func relativeBodyFilePath(path string, requestPath string) string {
+	dir, err := bodyFileDir(requestPath)
+	if err != nil {
+		return path
+	}
+	relativePath, err := filepath.Rel(dir, path)
+	if err != nil || strings.HasPrefix(relativePath, "..") {
+		return path
+	}
+	return relativePath
+}
+
+func openBodyFile(path string, requestPath string) (*os.File, int64, string, error) {
+	path, err := resolveBodyFilePath(path, requestPath)
+	if err != nil {
+		return nil, 0, "", err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, 0, "", err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, 0, "", err
+	}
+	return file, info.Size(), bodyFileContentType(path), nil
+}
+
+func bodyFileContentType(path string) string {
+	contentType := mime.TypeByExtension(filepath.Ext(path))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return contentType
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveBodyFilePath(t *testing.T) {
+	useTempVdatDir(t)
+	vdatDir, err := getVdatDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	outside := t.TempDir()
+	tests := []struct {
+		name        string
+		path        string
+		requestPath string
+		want        string
+	}{
+		{"unsaved", "data.bin", "", filepath.Join(vdatDir, "data.bin")},
+		{"in the vdat folder", "data.bin", filepath.Join(vdatDir, "GET - root"), filepath.Join(vdatDir, "data.bin")},
+		{"in a collection", "files/data.bin", filepath.Join(vdatDir, "shop", "GET - items"), filepath.Join(vdatDir, "shop", "files", "data.bin")},
+		{"in a collection subfolder", "files/data.bin", filepath.Join(vdatDir, "shop", "orders", "POST - order"), filepath.Join(vdatDir, "shop", "files", "data.bin")},
+		{"outside the vdat folder", "data.bin", filepath.Join(outside, "GET - elsewhere"), filepath.Join(outside, "data.bin")},
+		{"absolute", "/tmp/data.bin", filepath.Join(vdatDir, "shop", "GET - items"), "/tmp/data.bin"},
+		{"home", "~/data.bin", filepath.Join(vdatDir, "shop", "GET - items"), filepath.Join(home, "data.bin")},
+	}
+	for _, test := range tests {
+		got, err := resolveBodyFilePath(test.path, test.requestPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != test.want {
+			t.Errorf("%s: resolveBodyFilePath(%q) = %q, want %q", test.name, test.path, got, test.want)
+		}
+		if !filepath.IsAbs(test.path) && test.path[0] != '~' {
+			relative := relativeBodyFilePath(got, test.requestPath)
+			if relative != filepath.FromSlash(test.path) {
+				t.Errorf("%s: relativeBodyFilePath(%q) = %q, want %q", test.name, got, relative, test.path)
+			}
+		}
+	}
+}
+
+func TestBuildHttpRequestBodyFileFromCollection(t *testing.T) {
+	useTempVdatDir(t)
+	vdatDir, err := getVdatDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.MkdirAll(filepath.Join(vdatDir, "shop", "orders"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(vdatDir, "shop", "order.json"), []byte(`{"id": 1}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	requestPath := filepath.Join(vdatDir, "shop", "orders", "POST - order")
+	err = saveVdatRequest(requestPath, VdatRequest{
+		Url:        "https://example.com/orders",
+		RestMethod: "POST",
+		BodyType:   BODY_TYPE_FILE,
+		BodyFile:   "order.json",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vdatRequest, err := loadVdatRequest(requestPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := buildHttpRequest(vdatRequest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer req.Body.Close()
+	if req.ContentLength != 9 || req.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("ContentLength = %d, Content-Type = %q", req.ContentLength, req.Header.Get("Content-Type"))
+	}
+}
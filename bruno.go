@@ -0,0 +1,491 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var brunoFilePattern = regexp.MustCompile(`^@file\((.*)\)$`)
+var brunoContentTypePattern = regexp.MustCompile(`\s*@contentType\(([^)]*)\)$`)
+
+// brunoBlock is one "name {...}" block of a .bru file, its lines without
+// the two spaces of indentation.
+type brunoBlock struct {
+	name  string
+	lines []string
+}
+
+type brunoFile []brunoBlock
+
+func parseBrunoFile(text string) (brunoFile, error) {
+	blocks := brunoFile{}
+	var block *brunoBlock
+	closing := ""
+	for number, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		if block != nil {
+			if line == closing {
+				blocks = append(blocks, *block)
+				block = nil
+				continue
+			}
+			block.lines = append(block.lines, strings.TrimPrefix(line, "  "))
+			continue
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		switch {
+		case strings.HasSuffix(line, "{"):
+			closing = "}"
+		case strings.HasSuffix(line, "["):
+			closing = "]"
+		default:
+			return nil, errors.New(fmt.Sprint("Error reading .bru line ", number+1, ": ", line))
+		}
+		block = &brunoBlock{name: strings.TrimSpace(line[:len(line)-1])}
+	}
+	if block != nil {
+		return nil, errors.New(fmt.Sprint("Unclosed .bru block: ", block.name))
+	}
+	return blocks, nil
+}
+
+func (file brunoFile) block(name string) (brunoBlock, bool) {
+	for _, block := range file {
+		if block.name == name {
+			return block, true
+		}
+	}
+	return brunoBlock{}, false
+}
+
+// text reads a block written as plain text, like a body or a script.
+func (file brunoFile) text(name string) string {
+	block, _ := file.block(name)
+	return strings.TrimRight(strings.Join(block.lines, "\n"), "\n ")
+}
+
+// rows reads a block of "key: value" lines, a key starting with "~" is
+// disabled.
+func (file brunoFile) rows(name string) []KeyValueRow {
+	block, _ := file.block(name)
+	rows := []KeyValueRow{}
+	for _, line := range block.lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(line, ":")
+		row := KeyValueRow{KeyValue: KeyValue{Key: strings.TrimSpace(key), Value: strings.TrimSpace(value), HasValue: true}, Enabled: true}
+		if strings.HasPrefix(row.Key, "~") {
+			row.Key = row.Key[1:]
+			row.Enabled = false
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func (file brunoFile) value(blockName string, key string) string {
+	for _, row := range file.rows(blockName) {
+		if row.Key == key && row.Enabled {
+			return row.Value
+		}
+	}
+	return ""
+}
+
+var brunoOAuth2Grants = map[string]string{
+	"client_credentials": OAUTH2_GRANT_CLIENT_CREDENTIALS,
+	"password":           OAUTH2_GRANT_PASSWORD,
+	"authorization_code": OAUTH2_GRANT_AUTHORIZATION_CODE,
+}
+
+// brunoAuthToVdat reads the auth:<mode> block of a request, folder or
+// collection file.
+func brunoAuthToVdat(file brunoFile, mode string, collection *collectionImport, where string) VdatAuth {
+	block := "auth:" + mode
+	switch mode {
+	case "", "none":
+		return VdatAuth{Type: AUTH_TYPE_NONE}
+	case "basic":
+		return VdatAuth{Type: AUTH_TYPE_BASIC, Username: file.value(block, "username"), Password: file.value(block, "password")}
+	case "digest":
+		return VdatAuth{Type: AUTH_TYPE_DIGEST, Username: file.value(block, "username"), Password: file.value(block, "password")}
+	case "bearer":
+		return VdatAuth{Type: AUTH_TYPE_BEARER, Token: file.value(block, "token")}
+	case "apikey":
+		in := API_KEY_IN_HEADER
+		if file.value(block, "placement") == "queryparams" {
+			in = API_KEY_IN_QUERY
+		}
+		return VdatAuth{Type: AUTH_TYPE_API_KEY, Key: file.value(block, "key"), Value: file.value(block, "value"), In: in}
+	case "awsv4":
+		if file.value(block, "profileName") != "" {
+			collection.warn(where, "AWS profile names are not supported")
+		}
+		return VdatAuth{Type: AUTH_TYPE_AWS_SIGV4, AccessKey: file.value(block, "accessKeyId"), SecretKey: file.value(block, "secretAccessKey"), Region: file.value(block, "region"), Service: file.value(block, "service"), SessionToken: file.value(block, "sessionToken")}
+	case "oauth2":
+		grant, found := brunoOAuth2Grants[file.value(block, "grant_type")]
+		if !found {
+			grant = OAUTH2_GRANT_AUTHORIZATION_CODE
+			collection.warn(where, "OAuth 2.0 grant ", file.value(block, "grant_type"), " is not supported, using ", grant)
+		}
+		return VdatAuth{
+			Type:         AUTH_TYPE_OAUTH2,
+			Grant:        grant,
+			TokenUrl:     file.value(block, "access_token_url"),
+			AuthUrl:      file.value(block, "authorization_url"),
+			ClientId:     file.value(block, "client_id"),
+			ClientSecret: file.value(block, "client_secret"),
+			Scope:        file.value(block, "scope"),
+			Username:     file.value(block, "username"),
+			Password:     file.value(block, "password"),
+		}
+	}
+	collection.warn(where, "auth mode ", mode, " is not supported")
+	return VdatAuth{Type: AUTH_TYPE_NONE}
+}
+
+// brunoScope is what a request inherits from its folders and the
+// collection: headers, the auth used with "auth: inherit" and the
+// collection directory that @file paths start from.
+type brunoScope struct {
+	headers []KeyValueRow
+	auth    VdatAuth
+	root    string
+}
+
+// filePath makes a @file path absolute, the imported request is saved in
+// another folder than the one Bruno reads it from.
+func (scope brunoScope) filePath(path string, collection *collectionImport, where string) string {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(scope.root, path)
+	}
+	_, err := os.Stat(path)
+	if err != nil {
+		collection.warn(where, "file ", path, " does not exist")
+	}
+	return path
+}
+
+// enter adds the headers and auth of a collection.bru or folder.bru file.
+func (scope brunoScope) enter(file brunoFile, collection *collectionImport, where string) brunoScope {
+	headers := append([]KeyValueRow{}, scope.headers...)
+	scope.headers = append(headers, file.rows("headers")...)
+	if mode := file.value("auth", "mode"); mode != "" && mode != "inherit" {
+		scope.auth = brunoAuthToVdat(file, mode, collection, where)
+	}
+	warnBrunoScripts(file, collection, where)
+	return scope
+}
+
+func warnBrunoScripts(file brunoFile, collection *collectionImport, where string) {
+	for _, block := range file {
+		switch {
+		case strings.HasPrefix(block.name, "script:"), strings.HasPrefix(block.name, "vars:"), block.name == "tests", block.name == "assert":
+			if file.text(block.name) != "" {
+				collection.warn(where, block.name, " is not converted")
+			}
+		}
+	}
+}
+
+func brunoRequestToVdat(file brunoFile, scope brunoScope, collection *collectionImport, where string) (VdatRequest, error) {
+	vdatRequest := VdatRequest{
+		Title:      file.value("meta", "name"),
+		SslEnabled: true,
+		BodyType:   BODY_TYPE_NONE,
+	}
+	method := ""
+	for _, block := range file {
+		if containsString(REST_METHODS, strings.ToUpper(block.name)) || block.name == "connect" || block.name == "trace" {
+			method = block.name
+		}
+	}
+	if method == "" {
+		return VdatRequest{}, errors.New("no method block")
+	}
+	vdatRequest.RestMethod = collection.method(where, method)
+	warnBrunoScripts(file, collection, where)
+
+	base, query := splitUrlQuery(file.value(method, "url"))
+	vdatRequest.Url = base
+	if _, found := file.block("params:query"); found {
+		vdatRequest.Params = formatKeyValueRows(readableQueryRows(file.rows("params:query")), "=")
+	} else {
+		vdatRequest.Params = formatKeyValueLines(readableQueryPairs(query), "=")
+	}
+	vdatRequest.PathParams = formatKeyValueRows(file.rows("params:path"), "=")
+
+	if mode := file.value(method, "auth"); mode == "inherit" {
+		vdatRequest.Auth = scope.auth
+	} else {
+		vdatRequest.Auth = brunoAuthToVdat(file, mode, collection, where)
+	}
+
+	contentType := ""
+	switch body := file.value(method, "body"); body {
+	case "", "none":
+	case "json", "xml", "text", "sparql":
+		vdatRequest.BodyType = BODY_TYPE_RAW
+		vdatRequest.BodyContent = file.text("body:" + body)
+		vdatRequest.RawLanguage = map[string]string{"json": RAW_LANGUAGE_JSON, "xml": RAW_LANGUAGE_XML, "text": RAW_LANGUAGE_TEXT}[body]
+		if body == "sparql" {
+			contentType = "application/sparql-query"
+		}
+	case "graphql":
+		graphql := map[string]any{"query": file.text("body:graphql")}
+		variables := file.text("body:graphql:vars")
+		if variables != "" && json.Valid([]byte(variables)) {
+			graphql["variables"] = json.RawMessage(variables)
+		} else if variables != "" {
+			collection.warn(where, "GraphQL variables are not valid JSON and were left out")
+		}
+		content, _ := json.MarshalIndent(graphql, "", "  ")
+		vdatRequest.BodyType = BODY_TYPE_RAW
+		vdatRequest.RawLanguage = RAW_LANGUAGE_JSON
+		vdatRequest.BodyContent = string(content)
+	case "formUrlEncoded":
+		vdatRequest.BodyType = BODY_TYPE_FORM
+		vdatRequest.BodyContent = formatKeyValueRows(literalRows(file.rows("body:form-urlencoded")), "=")
+	case "multipartForm":
+		vdatRequest.BodyType = BODY_TYPE_MULTIPART
+		lines := []string{}
+		for _, row := range file.rows("body:multipart-form") {
+			parts := []VdatMultipartPart{}
+			contentType := ""
+			value := row.Value
+			if match := brunoContentTypePattern.FindStringSubmatch(value); match != nil {
+				contentType = match[1]
+				value = strings.TrimSuffix(value, match[0])
+			}
+			if match := brunoFilePattern.FindStringSubmatch(value); match != nil {
+				for _, path := range strings.Split(match[1], "|") {
+					parts = append(parts, VdatMultipartPart{Name: row.Key, Value: scope.filePath(path, collection, where), IsFile: true, ContentType: contentType})
+				}
+			} else {
+				if strings.HasPrefix(value, "@") || strings.HasPrefix(value, "<") {
+					collection.warn(where, "form value ", row.Key, " starts with ", value[:1], " and is read as a file")
+				}
+				parts = append(parts, VdatMultipartPart{Name: row.Key, Value: value, ContentType: contentType})
+			}
+			for _, part := range parts {
+				line := part.String()
+				if !row.Enabled {
+					line = "#" + line
+				}
+				lines = append(lines, line)
+			}
+		}
+		vdatRequest.BodyContent = strings.Join(lines, "\n")
+	case "file":
+		vdatRequest.BodyType = BODY_TYPE_FILE
+		for _, row := range file.rows("body:file") {
+			value := brunoContentTypePattern.ReplaceAllString(row.Value, "")
+			if match := brunoFilePattern.FindStringSubmatch(value); match != nil && row.Enabled {
+				vdatRequest.BodyFile = scope.filePath(match[1], collection, where)
+			}
+		}
+		if vdatRequest.BodyFile == "" {
+			collection.warn(where, "body file has no file selected")
+		}
+	default:
+		collection.warn(where, "body mode ", body, " is not supported")
+	}
+
+	// the folder and collection headers come first, the request's own
+	// replace them
+	headers := []KeyValueRow{}
+	requestHeaders := file.rows("headers")
+	for _, row := range scope.headers {
+		if !hasHeader(formatKeyValueRows(requestHeaders, "\t"), strings.TrimSpace(row.Key)) {
+			headers = append(headers, row)
+		}
+	}
+	headers = append(headers, requestHeaders...)
+	if contentType != "" && !hasHeader(formatKeyValueRows(headers, "\t"), "Content-Type") {
+		headers = append(headers, KeyValueRow{KeyValue: KeyValue{Key: "Content-Type", Value: contentType, HasValue: true}, Enabled: true})
+	}
+	vdatRequest.Headers = formatKeyValueRows(withoutImpliedContentType(headers, vdatRequest), "\t")
+
+	if strings.Contains(fmt.Sprint(vdatRequest), "{{process.env.") {
+		collection.warn(where, "process.env variables are not converted")
+	}
+	return vdatRequest, nil
+}
+
+func readBrunoFile(path string) (brunoFile, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseBrunoFile(string(content))
+}
+
+// importBrunoFolder imports the .bru files of a folder ordered by their
+// seq, then its subfolders.
+func importBrunoFolder(source string, folder string, scope brunoScope, collection *collectionImport, where string) error {
+	entries, err := os.ReadDir(source)
+	if err != nil {
+		return err
+	}
+	type brunoRequest struct {
+		seq  float64
+		name string
+		file brunoFile
+	}
+	requests := []brunoRequest{}
+	for _, entry := range entries {
+		name := entry.Name()
+		path := filepath.Join(source, name)
+		if entry.IsDir() || filepath.Ext(name) != BRUNO_EXTENSION || name == BRUNO_FOLDER_FILE || name == BRUNO_SETTINGS_FILE {
+			continue
+		}
+		file, err := readBrunoFile(path)
+		if err != nil {
+			collection.warn(where+"/"+name, err)
+			continue
+		}
+		if requestType := file.value("meta", "type"); requestType != "" && requestType != "http" && requestType != "graphql" {
+			collection.warn(where+"/"+name, requestType, " requests are not supported")
+			continue
+		}
+		seq, _ := strconv.ParseFloat(file.value("meta", "seq"), 64)
+		requests = append(requests, brunoRequest{seq: seq, name: name, file: file})
+	}
+	sort.SliceStable(requests, func(i, j int) bool { return requests[i].seq < requests[j].seq })
+	for _, request := range requests {
+		requestWhere := where + "/" + request.name
+		vdatRequest, err := brunoRequestToVdat(request.file, scope, collection, requestWhere)
+		if err != nil {
+			collection.warn(requestWhere, err)
+			continue
+		}
+		if vdatRequest.Title == "" {
+			vdatRequest.Title = strings.TrimSuffix(request.name, BRUNO_EXTENSION)
+		}
+		err = collection.addRequest(folder, vdatRequest)
+		if err != nil {
+			return err
+		}
+	}
+
+	// environments are read on their own, from the collection directory
+	_, err = os.Stat(filepath.Join(source, BRUNO_COLLECTION_FILE))
+	isCollection := err == nil
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() || strings.HasPrefix(name, ".") || name == "node_modules" || isCollection && name == BRUNO_ENVIRONMENTS_DIR {
+			continue
+		}
+		subfolderWhere := where + "/" + name
+		folderScope := scope
+		file, err := readBrunoFile(filepath.Join(source, name, BRUNO_FOLDER_FILE))
+		if err == nil {
+			if metaName := file.value("meta", "name"); metaName != "" {
+				name = metaName
+			}
+			folderScope = scope.enter(file, collection, subfolderWhere)
+		} else if !errors.Is(err, os.ErrNotExist) {
+			collection.warn(subfolderWhere, err)
+		}
+		subfolder, err := collection.addFolder(folder, name)
+		if err != nil {
+			return err
+		}
+		err = importBrunoFolder(filepath.Join(source, entry.Name()), subfolder, folderScope, collection, subfolderWhere)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// importBrunoEnvironments adds each environments/<name>.bru file as an
+// environment named "collection - name". Secret values are not stored in
+// the collection, so they are added empty.
+func importBrunoEnvironments(source string, name string, collection *collectionImport) error {
+	entries, err := os.ReadDir(filepath.Join(source, BRUNO_ENVIRONMENTS_DIR))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != BRUNO_EXTENSION {
+			continue
+		}
+		environmentName := name + " - " + strings.TrimSuffix(entry.Name(), BRUNO_EXTENSION)
+		file, err := readBrunoFile(filepath.Join(source, BRUNO_ENVIRONMENTS_DIR, entry.Name()))
+		if err != nil {
+			collection.warn("Environment "+environmentName, err)
+			continue
+		}
+		rows := file.rows("vars")
+		secrets, _ := file.block("vars:secret")
+		for _, line := range secrets.lines {
+			secret := strings.TrimSuffix(strings.TrimSpace(line), ",")
+			if secret == "" {
+				continue
+			}
+			row := KeyValueRow{KeyValue: KeyValue{Key: strings.TrimPrefix(secret, "~"), HasValue: true}, Enabled: !strings.HasPrefix(secret, "~")}
+			rows = append(rows, row)
+			collection.warn("Environment "+environmentName, "secret ", row.Key, " has no value in the collection")
+		}
+		err = collection.addEnvironment(environmentName, rows)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// importBrunoCollection reads a Bruno collection directory, the one holding
+// bruno.json, into a new folder under parent.
+func importBrunoCollection(source string, parent string, collection *collectionImport) error {
+	content, err := os.ReadFile(filepath.Join(source, BRUNO_COLLECTION_FILE))
+	if err != nil {
+		return err
+	}
+	var config struct {
+		Name string `json:"name"`
+	}
+	err = json.Unmarshal(content, &config)
+	if err != nil {
+		return errors.New(fmt.Sprint("Error reading ", BRUNO_COLLECTION_FILE, ": ", err))
+	}
+	if config.Name == "" {
+		config.Name = filepath.Base(source)
+	}
+
+	root, err := filepath.Abs(source)
+	if err != nil {
+		return err
+	}
+	scope := brunoScope{auth: VdatAuth{Type: AUTH_TYPE_NONE}, root: root}
+	file, err := readBrunoFile(filepath.Join(source, BRUNO_SETTINGS_FILE))
+	if err == nil {
+		scope = scope.enter(file, collection, config.Name)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		collection.warn(config.Name, err)
+	}
+	folder, err := collection.addCollection(parent, config.Name)
+	if err != nil {
+		return err
+	}
+	err = importBrunoFolder(source, folder, scope, collection, config.Name)
+	if err != nil {
+		return err
+	}
+	return importBrunoEnvironments(source, config.Name, collection)
+}
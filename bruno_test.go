@@ -0,0 +1,255 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFiles writes files under dir, creating the folders they are in
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(path, []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestImportBrunoFilePaths(t *testing.T) {
+	useTempVdatDir(t)
+	vdatDir, err := getVdatDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := t.TempDir()
+	writeFiles(t, source, map[string]string{
+		"bruno.json":     `{"version": "1", "name": "Shop", "type": "collection"}`,
+		"files/pic.png":  "png",
+		"files/data.bin": "bin",
+		"Upload.bru": `meta {
+  name: Upload
+  seq: 1
+}
+
+post {
+  url: https://example.com/upload
+  body: multipartForm
+  auth: none
+}
+
+body:multipart-form {
+  pic: @file(files/pic.png) @contentType(image/png)
+  gone: @file(files/missing.png)
+}
+`,
+		"Data.bru": `meta {
+  name: Data
+  seq: 2
+}
+
+put {
+  url: https://example.com/data
+  body: file
+  auth: none
+}
+
+body:file {
+  file: @file(files/data.bin) @contentType(application/octet-stream)
+}
+`,
+	})
+
+	collection, err := importCollection(filepath.Join(source, BRUNO_COLLECTION_FILE), vdatDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(collection.warnings) != 1 || !strings.Contains(collection.warnings[0], "files/missing.png does not exist") {
+		t.Errorf("warnings = %q", collection.warnings)
+	}
+
+	upload, err := loadVdatRequest(filepath.Join(vdatDir, "Shop", "POST - Upload"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "pic=@" + filepath.Join(source, "files", "pic.png") + ";type=image/png"
+	if !strings.HasPrefix(upload.BodyContent, want+"\n") {
+		t.Errorf("BodyContent = %q, want it to start with %q", upload.BodyContent, want)
+	}
+
+	data, err := loadVdatRequest(filepath.Join(vdatDir, "Shop", "PUT - Data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.BodyFile != filepath.Join(source, "files", "data.bin") {
+		t.Errorf("BodyFile = %q", data.BodyFile)
+	}
+	req, err := buildHttpRequest(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Body.Close()
+	if req.ContentLength != 3 {
+		t.Errorf("ContentLength = %d", req.ContentLength)
+	}
+}
+
+func TestParseBrunoFile(t *testing.T) {
+	file, err := parseBrunoFile(`meta {
+  name: Create
+  seq: 3
+}
+
+post {
+  url: https://{{host}}/items?draft=1
+  body: json
+  auth: inherit
+}
+
+params:query {
+  draft: 1
+  ~debug: true
+}
+
+headers {
+  Accept: application/json
+  ~X-Old: yes
+}
+
+body:json {
+  {
+    "item": {
+      "tags": ["a", "b"],
+      "size": {"w": 1}
+    }
+  }
+}
+
+vars:secret [
+  token,
+  ~old
+]
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file.value("meta", "name") != "Create" || file.value("post", "url") != "https://{{host}}/items?draft=1" {
+		t.Errorf("meta and post values = %q, %q", file.value("meta", "name"), file.value("post", "url"))
+	}
+	wantBody := "{\n  \"item\": {\n    \"tags\": [\"a\", \"b\"],\n    \"size\": {\"w\": 1}\n  }\n}"
+	if file.text("body:json") != wantBody {
+		t.Errorf("body = %q, want %q", file.text("body:json"), wantBody)
+	}
+	headers := file.rows("headers")
+	if len(headers) != 2 || !headers[0].Enabled || headers[1].Enabled || headers[1].Key != "X-Old" {
+		t.Errorf("headers = %+v", headers)
+	}
+	if file.value("headers", "X-Old") != "" {
+		t.Error("a disabled row has a value")
+	}
+	secrets, found := file.block("vars:secret")
+	if !found || len(secrets.lines) != 2 || strings.TrimSpace(secrets.lines[1]) != "~old" {
+		t.Errorf("vars:secret = %+v", secrets)
+	}
+
+	_, err = parseBrunoFile("meta {\n  name: x\n")
+	if err == nil || err.Error() != "Unclosed .bru block: meta" {
+		t.Errorf("unclosed err = %v", err)
+	}
+	_, err = parseBrunoFile("name: x")
+	if err == nil || err.Error() != "Error reading .bru line 1: name: x" {
+		t.Errorf("stray line err = %v", err)
+	}
+}
+
+func TestImportBrunoCollection(t *testing.T) {
+	useTempVdatDir(t)
+	vdatDir, err := getVdatDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := t.TempDir()
+	writeFiles(t, source, map[string]string{
+		"bruno.json": `{"version": "1", "name": "Shop", "type": "collection"}`,
+		"collection.bru": `headers {
+  X-Client: vdat
+}
+
+auth {
+  mode: bearer
+}
+
+auth:bearer {
+  token: {{token}}
+}
+`,
+		"Inherit.bru": "meta {\n  name: Inherit\n  seq: 1\n}\n\nget {\n  url: https://example.com/a\n  auth: inherit\n}\n",
+		"None.bru":    "meta {\n  name: None\n  seq: 2\n}\n\nget {\n  url: https://example.com/b\n  auth: none\n}\n\nheaders {\n  X-Client: own\n}\n",
+		"admin/folder.bru": `meta {
+  name: Admin
+}
+
+auth {
+  mode: basic
+}
+
+auth:basic {
+  username: admin
+  password: {{password}}
+}
+`,
+		"admin/Users.bru": "meta {\n  name: Users\n  seq: 1\n}\n\nget {\n  url: https://example.com/users\n  auth: inherit\n}\n",
+		"environments/dev.bru": `vars {
+  host: dev.example.com
+  ~port: 8080
+}
+
+vars:secret [
+  token
+]
+`,
+	})
+
+	collection, err := importCollection(filepath.Join(source, BRUNO_COLLECTION_FILE), vdatDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantWarnings := []string{"Environment Shop - dev: secret token has no value in the collection"}
+	if strings.Join(collection.warnings, "\n") != strings.Join(wantWarnings, "\n") {
+		t.Errorf("warnings = %q", collection.warnings)
+	}
+
+	tests := []struct {
+		file    string
+		auth    VdatAuth
+		headers string
+	}{
+		{"GET - Inherit", VdatAuth{Type: AUTH_TYPE_BEARER, Token: "{{token}}"}, "X-Client\tvdat"},
+		{"GET - None", VdatAuth{Type: AUTH_TYPE_NONE}, "X-Client\town"},
+		{filepath.Join("Admin", "GET - Users"), VdatAuth{Type: AUTH_TYPE_BASIC, Username: "admin", Password: "{{password}}"}, "X-Client\tvdat"},
+	}
+	for _, test := range tests {
+		got, err := loadVdatRequest(filepath.Join(vdatDir, "Shop", test.file))
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if got.Auth != test.auth || got.Headers != test.headers {
+			t.Errorf("%s: auth %+v, headers %q", test.file, got.Auth, got.Headers)
+		}
+	}
+
+	environment, err := loadEnvironment("Shop - dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if environment.Variables != "host=dev.example.com\n#port=8080\ntoken=" {
+		t.Errorf("Variables = %q", environment.Variables)
+	}
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+//go:embed codegen/*.tmpl
+var codegenFiles embed.FS
+
+// built in languages and their templates, in the order they are listed
+var codegenBuiltins = []struct {
+	name string
+	file string
+}{
+	{CODEGEN_GO, "codegen/go.tmpl"},
+	{CODEGEN_PYTHON, "codegen/python.tmpl"},
+	{CODEGEN_FETCH, "codegen/fetch.tmpl"},
+	{CODEGEN_AXIOS, "codegen/axios.tmpl"},
+	{CODEGEN_HTTPIE, "codegen/httpie.tmpl"},
+	{CODEGEN_POWERSHELL, "codegen/powershell.tmpl"},
+}
+
+// CodegenRequest is what a code template sees: the request as it would be
+// sent, with the environment applied and the implied headers filled in.
+type CodegenRequest struct {
+	Method         string
+	Url            string
+	Headers        []KeyValue
+	MergedHeaders  []KeyValue
+	ContentType    string
+	BodyType       string
+	Body           string
+	Form           []KeyValue
+	Parts          []VdatMultipartPart
+	HasFileParts   bool
+	HasPartHeaders bool
+	BodyFile       string
+	Insecure       bool
+	Digest         bool
+	Username       string
+	Password       string
+}
+
+var codegenFuncs = template.FuncMap{
+	"goString":    strconv.Quote,
+	"disposition": partDisposition,
+	"json":        jsonString,
+	"shell":       shellQuote,
+	"powershell":  powershellQuote,
+	"lower":       strings.ToLower,
+}
+
+func jsonString(text string) string {
+	content, err := json.Marshal(text)
+	if err != nil {
+		return `""`
+	}
+	return string(content)
+}
+
+func powershellQuote(text string) string {
+	return "'" + strings.ReplaceAll(text, "'", "''") + "'"
+}
+
+func getCodegenDir() (string, error) {
+	vdatDir, err := getVdatDir()
+	if err != nil {
+		return "", err
+	}
+	codegenDir := filepath.Join(vdatDir, CODEGEN_DIR)
+	err = os.MkdirAll(codegenDir, os.ModePerm)
+	return codegenDir, err
+}
+
+// loadCodegenTemplates returns the built in templates followed by any
+// <language>.tmpl files in the .codegen directory. A file named after a
+// built in language replaces it.
+func loadCodegenTemplates() ([]string, map[string]string, error) {
+	names := []string{}
+	templates := make(map[string]string)
+	for _, builtin := range codegenBuiltins {
+		content, err := codegenFiles.ReadFile(builtin.file)
+		if err != nil {
+			return nil, nil, err
+		}
+		names = append(names, builtin.name)
+		templates[builtin.name] = string(content)
+	}
+
+	codegenDir, err := getCodegenDir()
+	if err != nil {
+		return nil, nil, err
+	}
+	files, err := os.ReadDir(codegenDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != CODEGEN_EXTENSION {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(codegenDir, file.Name()))
+		if err != nil {
+			return nil, nil, err
+		}
+		name := strings.TrimSuffix(file.Name(), CODEGEN_EXTENSION)
+		if _, found := templates[name]; !found {
+			names = append(names, name)
+		}
+		templates[name] = string(content)
+	}
+	return names, templates, nil
+}
+
+// newCodegenRequest builds and signs the request the way sending does, so
+// the templates get the final url and headers. The request must already
+// have its environment applied.
+func newCodegenRequest(vdatRequest VdatRequest, now time.Time) (CodegenRequest, error) {
+	if vdatRequest.Auth.Type == AUTH_TYPE_OAUTH2 && vdatRequest.Auth.Token == "" {
+		return CodegenRequest{}, errors.New(NO_OAUTH2_TOKEN_TEXT)
+	}
+	req, err := buildHttpRequest(vdatRequest)
+	if err != nil {
+		return CodegenRequest{}, err
+	}
+	err = signRequest(req, vdatRequest, now)
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	if err != nil {
+		return CodegenRequest{}, err
+	}
+
+	codegenRequest := CodegenRequest{
+		Method:   req.Method,
+		Url:      req.URL.String(),
+		BodyType: vdatRequest.BodyType,
+		Insecure: !vdatRequest.SslEnabled,
+	}
+	if codegenRequest.BodyType == "" {
+		codegenRequest.BodyType = BODY_TYPE_NONE
+	}
+
+	// implied headers first, then the typed ones in the order written.
+	// Multipart content types carry a boundary the generated code makes.
+	implied := []string{}
+	for name := range req.Header {
+		if !hasHeader(vdatRequest.Headers, name) {
+			implied = append(implied, name)
+		}
+	}
+	sort.Strings(implied)
+	for _, name := range implied {
+		if name == "Content-Type" && vdatRequest.BodyType == BODY_TYPE_MULTIPART {
+			continue
+		}
+		for _, value := range req.Header[name] {
+			codegenRequest.Headers = append(codegenRequest.Headers, KeyValue{Key: name, Value: value, HasValue: true})
+		}
+	}
+	for _, pair := range parseKeyValueLines(vdatRequest.Headers, "\t") {
+		if pair.HasValue {
+			codegenRequest.Headers = append(codegenRequest.Headers, KeyValue{Key: strings.TrimSpace(pair.Key), Value: strings.TrimSpace(pair.Value), HasValue: true})
+		}
+	}
+	for _, header := range codegenRequest.Headers {
+		if strings.EqualFold(header.Key, "Content-Type") {
+			codegenRequest.ContentType = header.Value
+		}
+		merged := false
+		for index, existing := range codegenRequest.MergedHeaders {
+			if strings.EqualFold(existing.Key, header.Key) {
+				codegenRequest.MergedHeaders[index].Value += ", " + header.Value
+				merged = true
+			}
+		}
+		if !merged {
+			codegenRequest.MergedHeaders = append(codegenRequest.MergedHeaders, header)
+		}
+	}
+
+	if vdatRequest.Auth.Type == AUTH_TYPE_DIGEST && !hasHeader(vdatRequest.Headers, "Authorization") {
+		codegenRequest.Digest = true
+		codegenRequest.Username = vdatRequest.Auth.Username
+		codegenRequest.Password = vdatRequest.Auth.Password
+	}
+
+	switch vdatRequest.BodyType {
+	case BODY_TYPE_RAW:
+		codegenRequest.Body = vdatRequest.BodyContent
+	case BODY_TYPE_FORM:
+		pairs := parseKeyValueLines(vdatRequest.BodyContent, "=")
+		codegenRequest.Body, err = encodeKeyValues(pairs, "body")
+		if err != nil {
+			return CodegenRequest{}, err
+		}
+		for _, pair := range pairs {
+			codegenRequest.Form = append(codegenRequest.Form, KeyValue{Key: decodePercent(pair.Key), Value: decodePercent(pair.Value), HasValue: pair.HasValue})
+		}
+	case BODY_TYPE_MULTIPART:
+		codegenRequest.Parts, err = parseMultipartParts(vdatRequest.BodyContent)
+		if err != nil {
+			return CodegenRequest{}, err
+		}
+		for index, part := range codegenRequest.Parts {
+			// parts with a type or a filename of their own are written
+			// with their headers by templates whose helpers cannot set them
+			if part.ContentType != "" || !part.IsFile && part.Filename != "" {
+				codegenRequest.HasPartHeaders = true
+			}
+			if !part.IsFile && !part.FromFile {
+				continue
+			}
+			codegenRequest.HasFileParts = true
+			part.Value, err = resolveBodyFilePath(part.Value, vdatRequest.Path)
+			if err != nil {
+				return CodegenRequest{}, err
+			}
+			if part.IsFile && part.Filename == "" {
+				part.Filename = filepath.Base(part.Value)
+			}
+			codegenRequest.Parts[index] = part
+		}
+	case BODY_TYPE_FILE:
+		codegenRequest.BodyFile, err = resolveBodyFilePath(vdatRequest.BodyFile, vdatRequest.Path)
+		if err != nil {
+			return CodegenRequest{}, err
+		}
+	}
+	return codegenRequest, nil
+}
+
+func generateCode(name string, text string, codegenRequest CodegenRequest) (string, error) {
+	codeTemplate, err := template.New(name).Funcs(codegenFuncs).Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var builder strings.Builder
+	err = codeTemplate.Execute(&builder, codegenRequest)
+	if err != nil {
+		return "", err
+	}
+	return builder.String(), nil
+}
+
+func codegenPopUp(window fyne.Window, vdatRequest VdatRequest, environment VdatEnvironment) {
+	canvas := window.Canvas()
+	vdatRequest, err := prepareExportRequest(vdatRequest, environment)
+	if err != nil {
+		errorPopUp(canvas, err)
+		return
+	}
+	codegenRequest, err := newCodegenRequest(vdatRequest, time.Now())
+	if err != nil {
+		errorPopUp(canvas, err)
+		return
+	}
+	names, templates, err := loadCodegenTemplates()
+	if err != nil {
+		errorPopUp(canvas, err)
+		return
+	}
+
+	code := widget.NewMultiLineEntry()
+	code.TextStyle.Monospace = true
+	code.SetMinRowsVisible(20)
+	language := widget.NewSelect(names, func(name string) {
+		text, err := generateCode(name, templates[name], codegenRequest)
+		if err != nil {
+			text = fmt.Sprint("Error in ", name, " template: ", err)
+		}
+		code.SetText(text)
+	})
+	language.SetSelected(names[0])
+
+	var popUp *widget.PopUp
+	copyButton := widget.NewButton(COPY_BUTTON_TEXT, func() {
+		window.Clipboard().SetContent(code.Text)
+		popUp.Hide()
+	})
+	closeButton := widget.NewButton(CLOSE_BUTTON_TEXT, func() { popUp.Hide() })
+	modalContent := container.NewBorder(language, container.NewHBox(copyButton, closeButton), nil, nil, code)
+	popUp = widget.NewModalPopUp(modalContent, canvas)
+	popUp.Resize(fyne.NewSize(canvas.Size().Width*3/4, canvas.Size().Height*3/4))
+	popUp.Show()
+}
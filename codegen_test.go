@@ -0,0 +1,130 @@
+package main
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGenerateCode(t *testing.T) {
+	useTempVdatDir(t)
+	dir := t.TempDir()
+	upload := filepath.Join(dir, "pic.png")
+	err := os.WriteFile(upload, []byte("png"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names, templates, err := loadCodegenTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != len(codegenBuiltins) {
+		t.Fatalf("names = %q", names)
+	}
+
+	base := VdatRequest{
+		Url:        "https://api.example.com/items",
+		Params:     "q=a b",
+		RestMethod: "POST",
+		SslEnabled: true,
+		Headers:    "X-Trace\tit's \"quoted\"",
+		Auth:       VdatAuth{Type: AUTH_TYPE_BEARER, Token: "tok"},
+	}
+	tests := []struct {
+		bodyType string
+		edit     func(*VdatRequest)
+	}{
+		{BODY_TYPE_NONE, func(r *VdatRequest) { r.RestMethod = "GET"; r.SslEnabled = false }},
+		{BODY_TYPE_RAW, func(r *VdatRequest) { r.BodyContent = "{\"a\": \"`$x`\"}"; r.RawLanguage = RAW_LANGUAGE_JSON }},
+		{BODY_TYPE_FORM, func(r *VdatRequest) { r.BodyContent = "name=a%26b\nnote=x y" }},
+		{BODY_TYPE_MULTIPART, func(r *VdatRequest) {
+			r.BodyContent = "title=hello\npic=@" + upload + ";type=image/png\nraw=@" + upload + "\ntext=<" + upload + "\nnote={\"a\":1};type=application/json;filename=note.json"
+		}},
+		{BODY_TYPE_FILE, func(r *VdatRequest) { r.BodyFile = upload; r.RestMethod = "PUT" }},
+	}
+	for _, test := range tests {
+		vdatRequest := base
+		vdatRequest.BodyType = test.bodyType
+		test.edit(&vdatRequest)
+		codegenRequest, err := newCodegenRequest(vdatRequest, time.Now())
+		if err != nil {
+			t.Fatal(test.bodyType, err)
+		}
+		for _, name := range names {
+			code, err := generateCode(name, templates[name], codegenRequest)
+			if err != nil {
+				t.Errorf("%s %s: %v", name, test.bodyType, err)
+				continue
+			}
+			if !strings.Contains(code, "api.example.com/items") || strings.Contains(code, "<no value>") {
+				t.Errorf("%s %s: generated\n%s", name, test.bodyType, code)
+			}
+			if name == CODEGEN_GO {
+				checkGoCode(t, test.bodyType, code)
+			}
+		}
+	}
+}
+
+// the source importer is slow, one is shared so the standard library is
+// only type checked once
+var goImporter = importer.ForCompiler(token.NewFileSet(), "source", nil)
+
+// checkGoCode parses and type checks the generated program, which also
+// catches imports a body type does not use
+func checkGoCode(t *testing.T, bodyType string, code string) {
+	fileSet := token.NewFileSet()
+	file, err := parser.ParseFile(fileSet, "main.go", code, 0)
+	if err != nil {
+		t.Errorf("%s: %v\n%s", bodyType, err, code)
+		return
+	}
+	config := types.Config{Importer: goImporter}
+	_, err = config.Check("main", fileSet, []*ast.File{file}, nil)
+	if err != nil {
+		t.Errorf("%s: %v\n%s", bodyType, err, code)
+	}
+}
+
+func TestGenerateGoMultipartHeaders(t *testing.T) {
+	useTempVdatDir(t)
+	upload := filepath.Join(t.TempDir(), "pic.png")
+	vdatRequest := VdatRequest{
+		Url:         "https://api.example.com/upload",
+		RestMethod:  "POST",
+		BodyType:    BODY_TYPE_MULTIPART,
+		BodyContent: "pic=@" + upload + ";type=image/png\nplain=@" + upload,
+	}
+	err := os.WriteFile(upload, []byte("png"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	codegenRequest, err := newCodegenRequest(vdatRequest, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, templates, err := loadCodegenTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, err := generateCode(CODEGEN_GO, templates[CODEGEN_GO], codegenRequest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`header.Set("Content-Disposition", "form-data; name=\"pic\"; filename=\"pic.png\"")`,
+		`header.Set("Content-Type", "image/png")`,
+		`writer.CreateFormFile("plain", "pic.png")`,
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("missing %s in\n%s", want, code)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// collectionImport writes the folders, requests and environment of an
+// imported collection and collects what could not be converted.
+type collectionImport struct {
+	folders  []string
+	requests int
+	warnings []string
+}
+
+func (collection *collectionImport) warn(where string, message ...any) {
+	collection.warnings = append(collection.warnings, fmt.Sprint(where, ": ", fmt.Sprint(message...)))
+}
+
+// looseString accepts the strings, numbers, booleans, nulls and
+// {"content": ...} descriptions exports write where a string is expected.
+type looseString string
+
+func (text *looseString) UnmarshalJSON(data []byte) error {
+	var value any
+	err := json.Unmarshal(data, &value)
+	if err != nil {
+		return err
+	}
+	switch value := value.(type) {
+	case nil:
+		*text = ""
+	case string:
+		*text = looseString(value)
+	case map[string]any:
+		content, _ := value["content"].(string)
+		*text = looseString(content)
+	default:
+		*text = looseString(bytes.TrimSpace(data))
+	}
+	return nil
+}
+
+// safeFileName keeps names usable as a single file tree entry. Names
+// starting with "." would be hidden from the tree.
+func safeFileName(name string) string {
+	name = strings.TrimSpace(strings.NewReplacer("/", "_", "\\", "_", "\n", " ", "\r", " ", "\x00", "").Replace(name))
+	name = strings.TrimLeft(name, ".")
+	if name == "" {
+		return TITLE_DEFAULT
+	}
+	return name
+}
+
+// uniquePath adds " (2)", " (3)" and so on until the path is free.
+func uniquePath(path string) string {
+	candidate := path
+	for index := 2; ; index++ {
+		_, err := os.Stat(candidate)
+		if os.IsNotExist(err) {
+			return candidate
+		}
+		candidate = fmt.Sprint(path, " (", index, ")")
+	}
+}
+
+func (collection *collectionImport) addFolder(parent string, name string) (string, error) {
+	folder := uniquePath(filepath.Join(parent, safeFileName(name)))
+	err := os.MkdirAll(folder, os.ModePerm)
+	return folder, err
+}
+
+// addCollection adds the top folder of a collection.
+func (collection *collectionImport) addCollection(parent string, name string) (string, error) {
+	folder, err := collection.addFolder(parent, name)
+	if err == nil {
+		collection.folders = append(collection.folders, filepath.Base(folder))
+	}
+	return folder, err
+}
+
+func (collection *collectionImport) method(where string, method string) string {
+	method = strings.ToUpper(strings.TrimSpace(method))
+	if method == "" {
+		return REST_METHODS[0]
+	}
+	if !containsString(REST_METHODS, method) {
+		collection.warn(where, "method ", method, " is not supported, using ", REST_METHODS[0])
+		return REST_METHODS[0]
+	}
+	return method
+}
+
+// literalRowText keeps text that was not typed for a url readable as a
+// param or form row, escaping it only where it would be decoded or split.
+func literalRowText(text string, reserved string) string {
+	if strings.ContainsAny(text, "%\n\r"+reserved) || strings.HasPrefix(text, "#") {
+		return url.PathEscape(text)
+	}
+	return text
+}
+
+func literalRows(rows []KeyValueRow) []KeyValueRow {
+	for index, row := range rows {
+		rows[index].Key = literalRowText(row.Key, "=")
+		rows[index].Value = literalRowText(row.Value, "")
+	}
+	return rows
+}
+
+// withoutImpliedContentType leaves out a Content-Type header the body type
+// sets anyway, a multipart one would otherwise lose its boundary.
+func withoutImpliedContentType(rows []KeyValueRow, vdatRequest VdatRequest) []KeyValueRow {
+	implied, _, _ := strings.Cut(impliedContentType(vdatRequest), ";")
+	kept := []KeyValueRow{}
+	for _, row := range rows {
+		mediaType, _, _ := strings.Cut(row.Value, ";")
+		if row.Enabled && implied != "" && strings.EqualFold(strings.TrimSpace(row.Key), "Content-Type") && strings.EqualFold(strings.TrimSpace(mediaType), implied) {
+			continue
+		}
+		kept = append(kept, row)
+	}
+	return kept
+}
+
+// addRequest saves a request with the same "METHOD - Title" name the SAVE
+// button uses.
+func (collection *collectionImport) addRequest(folder string, vdatRequest VdatRequest) error {
+	if vdatRequest.Title == "" {
+		vdatRequest.Title = TITLE_DEFAULT
+	}
+	if vdatRequest.RestMethod == "" {
+		vdatRequest.RestMethod = REST_METHODS[0]
+	}
+	if vdatRequest.BodyType == "" {
+		vdatRequest.BodyType = BODY_TYPE_NONE
+	}
+	filename := uniquePath(filepath.Join(folder, safeFileName(fmt.Sprint(vdatRequest.RestMethod, " - ", vdatRequest.Title))))
+	err := saveVdatRequest(filename, vdatRequest)
+	if err != nil {
+		return err
+	}
+	collection.requests++
+	return nil
+}
+
+// addEnvironment merges variables into an environment, keeping the values
+// of variables it already has.
+func (collection *collectionImport) addEnvironment(name string, rows []KeyValueRow) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	name = safeFileName(name)
+	environment, err := loadEnvironment(name)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	environment.Name = name
+	existing := parseKeyValueRows(environment.Variables, "=")
+	added := false
+	for _, row := range rows {
+		if row.Key == "" || !variablePattern.MatchString("{{"+row.Key+"}}") {
+			collection.warn("Environment "+name, "variable name not supported: ", row.Key)
+			continue
+		}
+		found := false
+		for _, existingRow := range existing {
+			if strings.TrimSpace(existingRow.Key) == row.Key {
+				found = true
+			}
+		}
+		if found {
+			collection.warn("Environment "+name, "kept the existing value of ", row.Key)
+			continue
+		}
+		// a value with a newline would split the variable line
+		if strings.ContainsAny(row.Value, "\r\n") {
+			collection.warn("Environment "+name, "multi-line value of ", row.Key, " joined into one line")
+			row.Value = strings.Join(strings.Fields(row.Value), " ")
+		}
+		row.HasValue = true
+		existing = append(existing, row)
+		added = true
+	}
+	if !added {
+		return nil
+	}
+	environment.Variables = formatKeyValueRows(existing, "=")
+	return saveEnvironment(environment)
+}
+
+func (collection *collectionImport) summary() string {
+	text := fmt.Sprint("Imported ", collection.requests, " requests into ", strings.Join(collection.folders, ", "))
+	if len(collection.warnings) != 0 {
+		text += "\n\n" + COLLECTION_WARNINGS_TEXT + "\n" + strings.Join(collection.warnings, "\n")
+	}
+	return text
+}
+
+// importCollection detects the format of a collection file and imports it
+// into new folders under parent. A Bruno collection is imported from its
+// bruno.json file.
+func importCollection(path string, parent string) (*collectionImport, error) {
+	collection := &collectionImport{}
+	if filepath.Base(path) == BRUNO_COLLECTION_FILE {
+		return collection, importBrunoCollection(filepath.Dir(path), parent, collection)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var probe struct {
+		Info struct {
+			Schema string `json:"schema"`
+		} `json:"info"`
+		Type         string `json:"_type"`
+		ExportFormat int    `json:"__export_format"`
+	}
+	err = json.Unmarshal(content, &probe)
+	switch {
+	case err != nil:
+	case strings.Contains(probe.Info.Schema, "getpostman.com"):
+		return collection, importPostmanCollection(content, parent, collection)
+	case probe.Type == "export" && probe.ExportFormat == 4:
+		return collection, importInsomniaExport(content, parent, collection)
+	}
+	return nil, errors.New(fmt.Sprint("Unsupported collection format: ", filepath.Base(path)))
+}
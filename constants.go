@@ -1,13 +1,144 @@
 package main
 
-import "net/http"
+import (
+	"net/http"
+	"time"
+)
 
 const APP_NAME = "vdat"
+const APP_ID = "io.github.christianwsmith.vdat"
+
+const RUN_COMMAND = "run"
+const RUN_USAGE = "usage: vdat run [flags] <request file or folder>..."
+
+const ENVIRONMENTS_DIR = ".environments"
+const TOKENS_FILE = ".tokens"
+const TLS_DIR = ".tls"
+const CODEGEN_DIR = ".codegen"
+const CODEGEN_EXTENSION = ".tmpl"
+
+const DEFAULT_CONNECT_TIMEOUT = "10s"
+const DEFAULT_TLS_HANDSHAKE_TIMEOUT = "10s"
+const DEFAULT_RESPONSE_HEADER_TIMEOUT = "30s"
+const DEFAULT_TOTAL_TIMEOUT = "60s"
+const MAX_CACHED_TRANSPORTS = 16
+
+const PREFERENCE_CONNECT_TIMEOUT = "timeouts.connect"
+const PREFERENCE_TLS_HANDSHAKE_TIMEOUT = "timeouts.tlsHandshake"
+const PREFERENCE_RESPONSE_HEADER_TIMEOUT = "timeouts.responseHeader"
+const PREFERENCE_TOTAL_TIMEOUT = "timeouts.total"
+const PREFERENCE_PROXY_URL = "proxy.url"
+const PREFERENCE_PROXY_USERNAME = "proxy.username"
+const PREFERENCE_PROXY_PASSWORD = "proxy.password"
+const PREFERENCE_NO_PROXY = "proxy.noProxy"
 
 const BODY_TYPE_FORM = "FORM"
 const BODY_TYPE_RAW = "RAW"
+const BODY_TYPE_MULTIPART = "MULTIPART"
+const BODY_TYPE_FILE = "FILE"
 const BODY_TYPE_NONE = "NONE"
 
+const AUTH_TYPE_NONE = "None"
+const AUTH_TYPE_BASIC = "Basic"
+const AUTH_TYPE_BEARER = "Bearer"
+const AUTH_TYPE_API_KEY = "API Key"
+const AUTH_TYPE_DIGEST = "Digest"
+const AUTH_TYPE_OAUTH2 = "OAuth 2.0"
+const AUTH_TYPE_AWS_SIGV4 = "AWS Signature V4"
+const AUTH_TYPE_HMAC = "HMAC"
+
+var AUTH_TYPES = []string{
+	AUTH_TYPE_NONE,
+	AUTH_TYPE_BASIC,
+	AUTH_TYPE_BEARER,
+	AUTH_TYPE_API_KEY,
+	AUTH_TYPE_DIGEST,
+	AUTH_TYPE_OAUTH2,
+	AUTH_TYPE_AWS_SIGV4,
+	AUTH_TYPE_HMAC}
+
+const API_KEY_IN_HEADER = "Header"
+const API_KEY_IN_QUERY = "Query"
+
+var API_KEY_LOCATIONS = []string{
+	API_KEY_IN_HEADER,
+	API_KEY_IN_QUERY}
+
+const OAUTH2_GRANT_CLIENT_CREDENTIALS = "Client Credentials"
+const OAUTH2_GRANT_PASSWORD = "Password"
+const OAUTH2_GRANT_REFRESH_TOKEN = "Refresh Token"
+const OAUTH2_GRANT_AUTHORIZATION_CODE = "Authorization Code (PKCE)"
+
+var OAUTH2_GRANTS = []string{
+	OAUTH2_GRANT_CLIENT_CREDENTIALS,
+	OAUTH2_GRANT_PASSWORD,
+	OAUTH2_GRANT_REFRESH_TOKEN,
+	OAUTH2_GRANT_AUTHORIZATION_CODE}
+
+const HMAC_ALGORITHM_SHA1 = "SHA-1"
+const HMAC_ALGORITHM_SHA256 = "SHA-256"
+const HMAC_ALGORITHM_SHA512 = "SHA-512"
+
+var HMAC_ALGORITHMS = []string{
+	HMAC_ALGORITHM_SHA1,
+	HMAC_ALGORITHM_SHA256,
+	HMAC_ALGORITHM_SHA512}
+
+const HMAC_SIGNS_BODY = "Body"
+const HMAC_SIGNS_REQUEST = "Method, URI, Date and body hash"
+
+var HMAC_SIGNS = []string{
+	HMAC_SIGNS_BODY,
+	HMAC_SIGNS_REQUEST}
+
+const HMAC_ENCODING_HEX = "Hex"
+const HMAC_ENCODING_BASE64 = "Base64"
+
+var HMAC_ENCODINGS = []string{
+	HMAC_ENCODING_HEX,
+	HMAC_ENCODING_BASE64}
+
+const HMAC_DEFAULT_HEADER = "X-Signature"
+
+const CODEGEN_GO = "Go net/http"
+const CODEGEN_PYTHON = "Python requests"
+const CODEGEN_FETCH = "JavaScript fetch"
+const CODEGEN_AXIOS = "Node axios"
+const CODEGEN_HTTPIE = "HTTPie"
+const CODEGEN_POWERSHELL = "PowerShell"
+
+const TLS_VERSION_DEFAULT = "Default"
+const TLS_EXPIRY_WARNING = 30 * 24 * time.Hour
+
+var TLS_VERSIONS = []string{
+	TLS_VERSION_DEFAULT,
+	"1.0",
+	"1.1",
+	"1.2",
+	"1.3"}
+
+const OAUTH2_EXPIRY_MARGIN = 30 * time.Second
+const OAUTH2_AUTHORIZE_TIMEOUT = 5 * time.Minute
+const OAUTH2_REDIRECT_ADDRESS = "127.0.0.1:0"
+const OAUTH2_REDIRECT_PATH = "/callback"
+const OAUTH2_CALLBACK_TEXT = "Authorized, you can close this window and return to vdat."
+
+const CONTENT_TYPE_FORM = "application/x-www-form-urlencoded"
+const CONTENT_TYPE_MULTIPART = "multipart/form-data; boundary=<generated>"
+
+const RAW_LANGUAGE_JSON = "JSON"
+const RAW_LANGUAGE_XML = "XML"
+const RAW_LANGUAGE_HTML = "HTML"
+const RAW_LANGUAGE_TEXT = "Text"
+const RAW_LANGUAGE_NDJSON = "NDJSON"
+
+var RAW_LANGUAGES = []string{
+	RAW_LANGUAGE_JSON,
+	RAW_LANGUAGE_XML,
+	RAW_LANGUAGE_HTML,
+	RAW_LANGUAGE_TEXT,
+	RAW_LANGUAGE_NDJSON}
+
 var REST_METHODS = []string{
 	http.MethodGet,
 	http.MethodHead,
@@ -19,35 +150,129 @@ var REST_METHODS = []string{
 	http.MethodOptions,
 	http.MethodTrace}
 
-var VALID_RUNES = []rune{
-	'A', 'B', 'C', 'D', 'E', 'F', 'G', 'H', 'I', 'J', 'K', 'L', 'M', 'N', 'O', 'P', 'Q', 'R', 'S', 'T', 'U', 'V', 'W', 'X', 'Y', 'Z',
-	'a', 'b', 'c', 'd', 'e', 'f', 'g', 'h', 'i', 'j', 'k', 'l', 'm', 'n', 'o', 'p', 'q', 'r', 's', 't', 'u', 'v', 'w', 'x', 'y', 'z',
-	'0', '1', '2', '3', '4', '5', '6', '7', '8', '9',
-	' ', '-', '_', '.', '~', '!', '#', '$', '&', '(', ')', '*', '+', ',', '/', ':', ';', '=', '?', '@', '[', ']'}
-
-const HEADERS_PLACEHOLDER = "# comment\nheader1 <tab> value1\nheader2 <tab> value2"
+const HEADERS_PLACEHOLDER = "# comment\nheader1 <tab> value1\nheader2 <tab> value2\nheader2 <tab> another value2"
 const PARAMS_PLACEHOLDER = "# comment\nparam1=value1\nparam2=value2"
+const PATH_PARAMS_PLACEHOLDER = "# filled from :name and {name} segments of the url\nname1=value1"
 const BODY_CONTENT_PLACEHOLDER_TYPE_NONE = ""
 const BODY_CONTENT_PLACEHOLDER_TYPE_FORM = "# comment\nbody1=value1\nbody2=value2"
+const BODY_CONTENT_PLACEHOLDER_TYPE_MULTIPART = "# comment\nfield1=value1\nfile1=@path/to/file;type=image/png;filename=name.png\nfield2=<path/to/text/file"
 const BODY_CONTENT_PLACEHOLDER_TYPE_RAW = "{\n    \"body1\": \"value1\",\n    \"body2\": \"value2\"\n}"
+const RAW_LANGUAGE_PLACEHOLDER = "<language>"
+const BODY_FILE_PLACEHOLDER = "<file path, absolute or relative to the collection folder>"
 const RESPONSE_STATUS_PLACEHOLDER = "<response status>"
 const RESPONSE_BODY_PLACEHOLDER = "<response body>"
+const RESPONSE_HEADERS_PLACEHOLDER = "<response headers>"
+const RESPONSE_COOKIES_PLACEHOLDER = "<response cookies>"
+const RESPONSE_INFO_PLACEHOLDER = "<response info>"
+const RESPONSE_TIMING_PLACEHOLDER = "<response timing>"
+const RESPONSE_TLS_PLACEHOLDER = "<response TLS connection>"
 const RESPONSE_TIME_PLACEHOLDER = "<response time>"
 const URL_PLACEHOLDER = "<url>"
+const PROXY_URL_PLACEHOLDER = "http://host:port, https://host:port or socks5://host:port"
+const NO_PROXY_PLACEHOLDER = "localhost, .internal.example.com, 10.0.0.0/8, * for all"
+const ENVIRONMENT_PLACEHOLDER = "<environment>"
+const ENVIRONMENT_VARIABLES_PLACEHOLDER = "# comment\nvariable1=value1\nvariable2=value2"
 const TITLE_PLACEHOLDER = "<title>"
 
 const TABS_PARAMS = "Params"
+const TABS_PATH_PARAMS = "Path Params"
+const TABS_AUTH = "Auth"
 const TABS_HEADERS = "Headers"
 const TABS_BODY = "Body"
+const TABS_TLS = "TLS"
+const TABS_SETTINGS = "Settings"
+const TABS_COOKIES = "Cookies"
+const TABS_INFO = "Info"
+const TABS_TIMING = "Timing"
+
+const KEY_COLUMN_TEXT = "Key"
+const VALUE_COLUMN_TEXT = "Value"
+const DESCRIPTION_COLUMN_TEXT = "Description"
+
+const AUTH_TYPE_TEXT = "Type"
+const USERNAME_TEXT = "Username"
+const PASSWORD_TEXT = "Password"
+const TOKEN_TEXT = "Token"
+const API_KEY_NAME_TEXT = "Key"
+const API_KEY_VALUE_TEXT = "Value"
+const API_KEY_IN_TEXT = "Add to"
+const TOKEN_URL_TEXT = "Token URL"
+const AUTH_URL_TEXT = "Auth URL"
+const CLIENT_ID_TEXT = "Client ID"
+const CLIENT_SECRET_TEXT = "Client secret"
+const SCOPE_TEXT = "Scope"
+const REFRESH_TOKEN_TEXT = "Refresh token"
+const REGION_TEXT = "Region"
+const SERVICE_TEXT = "Service"
+const ACCESS_KEY_TEXT = "Access key"
+const SECRET_KEY_TEXT = "Secret key"
+const SESSION_TOKEN_TEXT = "Session token"
+const HMAC_KEY_TEXT = "Key"
+const HMAC_ALGORITHM_TEXT = "Algorithm"
+const HMAC_HEADER_TEXT = "Header"
+const HMAC_SIGNS_TEXT = "Sign"
+const HMAC_ENCODING_TEXT = "Encoding"
+
+const CA_FILE_TEXT = "CA bundle"
+const CERT_FILE_TEXT = "Client certificate"
+const KEY_FILE_TEXT = "Client key"
+const CERT_PASSWORD_TEXT = "Certificate password"
+const SERVER_NAME_TEXT = "SNI server name"
+const MIN_TLS_VERSION_TEXT = "Min TLS version"
+const MAX_TLS_VERSION_TEXT = "Max TLS version"
+const TLS_HOST_TEXT = "TLS settings for all requests to "
+
+const CONNECT_TIMEOUT_TEXT = "Connect timeout"
+const TLS_HANDSHAKE_TIMEOUT_TEXT = "TLS handshake timeout"
+const RESPONSE_HEADER_TIMEOUT_TEXT = "Response header timeout"
+const TOTAL_TIMEOUT_TEXT = "Total timeout"
+const SETTINGS_TIMEOUTS_TEXT = "Default timeouts (e.g. 500ms, 30s, 0 for none)"
+const SETTINGS_PROXY_TEXT = "Proxy (empty to use the HTTP_PROXY environment variables)"
+const PROXY_URL_TEXT = "Proxy URL"
+const PROXY_USERNAME_TEXT = "Proxy username"
+const PROXY_PASSWORD_TEXT = "Proxy password"
+const NO_PROXY_TEXT = "No proxy for"
+const SENDING_TEXT = "sending..."
+const CANCELLED_TEXT = "cancelled"
 
 const TITLE_DEFAULT = "untitled"
+const NO_ENVIRONMENT_TEXT = "No environment"
+const CURL_WARNINGS_TEXT = "Imported with warnings:"
+const CURL_EXPORT_TEXT = "curl command"
+const NO_OAUTH2_TOKEN_TEXT = "No OAuth 2.0 token yet, send the request to fetch one"
+const COLLECTION_WARNINGS_TEXT = "Not converted:"
+const POSTMAN_SCHEMA = "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"
+const POSTMAN_EXTENSION = ".postman_collection.json"
+const POSTMAN_VARIABLES_TEXT = "Export these variables of the environment as collection variables? Anyone with the file can read them."
+const BRUNO_COLLECTION_FILE = "bruno.json"
+const BRUNO_SETTINGS_FILE = "collection.bru"
+const BRUNO_FOLDER_FILE = "folder.bru"
+const BRUNO_ENVIRONMENTS_DIR = "environments"
+const BRUNO_EXTENSION = ".bru"
 
-const SSL_ENABLED_TEXT = "SSL"
+const SSL_ENABLED_TEXT = "Verify server certificate"
 const SEND_BUTTON_TEXT = "SEND"
+const BROWSE_BUTTON_TEXT = "BROWSE"
+const BEAUTIFY_BUTTON_TEXT = "BEAUTIFY"
+const CANCEL_BUTTON_TEXT = "CANCEL"
+const CLEAR_TOKEN_BUTTON_TEXT = "CLEAR TOKEN"
+const HOST_TLS_BUTTON_TEXT = "HOST TLS"
+const ADD_ROW_BUTTON_TEXT = "ADD ROW"
+const REMOVE_ROW_BUTTON_TEXT = "X"
+const BULK_EDIT_BUTTON_TEXT = "BULK EDIT"
+const TABLE_BUTTON_TEXT = "TABLE"
 const SAVE_BUTTON_TEXT = "SAVE"
 const IMPORT_BUTTON_TEXT = "IMPORT FROM CURL"
+const EXPORT_CURL_BUTTON_TEXT = "COPY AS CURL"
+const CODEGEN_BUTTON_TEXT = "GENERATE CODE"
+const IMPORT_COLLECTION_BUTTON_TEXT = "IMPORT COLLECTION"
+const EXPORT_POSTMAN_BUTTON_TEXT = "EXPORT POSTMAN"
+const COPY_BUTTON_TEXT = "COPY"
 const NEW_BUTTON_TEXT = "NEW"
 const CLOSE_BUTTON_TEXT = "CLOSE"
+const SETTINGS_BUTTON_TEXT = "SETTINGS"
+const NEW_ENVIRONMENT_BUTTON_TEXT = "NEW ENV"
+const EDIT_ENVIRONMENT_BUTTON_TEXT = "EDIT ENV"
 const OK_BUTTON_TEXT = "OK"
 const YES_BUTTON_TEXT = "YES"
 const NO_BUTTON_TEXT = "NO"
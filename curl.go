@@ -0,0 +1,999 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// curl options that take a value, by long name, and the long name of each
+// short option
+var curlOptionsWithValue = map[string]bool{
+	"--request": true, "--header": true, "--data": true, "--data-raw": true,
+	"--data-ascii": true, "--data-binary": true, "--data-urlencode": true,
+	"--json": true, "--form": true, "--form-string": true, "--url": true,
+	"--user": true, "--cookie": true, "--user-agent": true, "--referer": true,
+	"--cacert": true, "--cert": true, "--key": true, "--cert-type": true,
+	"--key-type": true, "--proxy": true, "--proxy-user": true, "--socks5": true,
+	"--socks5-hostname": true, "--noproxy": true, "--connect-timeout": true,
+	"--max-time": true, "--oauth2-bearer": true, "--upload-file": true,
+	"--output": true, "--cookie-jar": true, "--write-out": true,
+	"--max-redirs": true, "--retry": true, "--range": true, "--resolve": true,
+	"--connect-to": true, "--interface": true, "--dump-header": true,
+	"--trace": true, "--trace-ascii": true, "--config": true,
+	"--aws-sigv4": true, "--pass": true, "--tls-max": true,
+	"--limit-rate": true, "--ciphers": true, "--tls13-ciphers": true,
+	"--curves": true, "--proto": true, "--proto-redir": true,
+	"--proto-default": true, "--retry-delay": true, "--retry-max-time": true,
+	"--speed-limit": true, "--speed-time": true, "--keepalive-time": true,
+	"--expect100-timeout": true, "--max-filesize": true, "--dns-servers": true,
+	"--local-port": true, "--unix-socket": true, "--abstract-unix-socket": true,
+	"--capath": true, "--crlfile": true, "--pinnedpubkey": true,
+	"--proxy-header": true, "--proxy-cacert": true, "--proxy-cert": true,
+	"--proxy-key": true, "--preproxy": true, "--request-target": true,
+	"--url-query": true, "--time-cond": true, "--netrc-file": true,
+	"--stderr": true, "--output-dir": true, "--alt-svc": true, "--hsts": true,
+	"--etag-save": true, "--etag-compare": true, "--variable": true,
+	"--doh-url": true, "--happy-eyeballs-timeout-ms": true, "--rate": true,
+	"--parallel-max": true, "--create-file-mode": true, "--trace-config": true,
+}
+
+var curlShortOptions = map[byte]string{
+	'X': "--request", 'H': "--header", 'd': "--data", 'F': "--form",
+	'u': "--user", 'b': "--cookie", 'A': "--user-agent", 'e': "--referer",
+	'E': "--cert", 'x': "--proxy", 'U': "--proxy-user", 'm': "--max-time",
+	'T': "--upload-file", 'o': "--output", 'c': "--cookie-jar",
+	'w': "--write-out", 'r': "--range", 'D': "--dump-header", 'K': "--config",
+	'G': "--get", 'I': "--head", 'L': "--location", 'k': "--insecure",
+	's': "--silent", 'S': "--show-error", 'v': "--verbose", 'i': "--include",
+	'f': "--fail", 'g': "--globoff", 'N': "--no-buffer", '#': "--progress-bar",
+	'O': "--remote-name", 'J': "--remote-header-name",
+}
+
+// options that change nothing about the request vdat sends
+var curlIgnoredOptions = map[string]bool{
+	"--location": true, "--silent": true, "--show-error": true,
+	"--verbose": true, "--include": true, "--fail": true, "--globoff": true,
+	"--no-buffer": true, "--progress-bar": true, "--output": true,
+	"--remote-name": true, "--remote-header-name": true, "--write-out": true,
+	"--dump-header": true, "--trace": true, "--trace-ascii": true,
+	"--max-redirs": true, "--retry": true, "--cookie-jar": true,
+	"--compressed": true, "--http1.1": true, "--http2": true,
+	"--cert-type": true, "--key-type": true, "--limit-rate": true,
+	"--retry-delay": true, "--retry-max-time": true, "--speed-limit": true,
+	"--speed-time": true, "--keepalive-time": true, "--max-filesize": true,
+	"--stderr": true, "--output-dir": true, "--etag-save": true,
+	"--parallel-max": true, "--create-file-mode": true, "--trace-config": true,
+	"--rate": true, "--expect100-timeout": true, "--happy-eyeballs-timeout-ms": true,
+}
+
+type curlOption struct {
+	name  string
+	value string
+}
+
+// tokenizeCurlCommand splits a command the way a POSIX shell would, including
+// backslash-newline continuations and $'...' quoting.
+func tokenizeCurlCommand(command string) ([]string, error) {
+	tokens := []string{}
+	var token strings.Builder
+	inToken := false
+	for index := 0; index < len(command); index++ {
+		char := command[index]
+		switch {
+		case char == '\\':
+			if index+1 < len(command) && command[index+1] == '\r' && index+2 < len(command) && command[index+2] == '\n' {
+				index += 2
+				continue
+			}
+			if index+1 < len(command) && command[index+1] == '\n' {
+				index++
+				continue
+			}
+			if index+1 < len(command) {
+				index++
+				token.WriteByte(command[index])
+			}
+			inToken = true
+		case char == ' ' || char == '\t' || char == '\n' || char == '\r':
+			if inToken {
+				tokens = append(tokens, token.String())
+				token.Reset()
+				inToken = false
+			}
+		case char == '\'':
+			end := strings.IndexByte(command[index+1:], '\'')
+			if end < 0 {
+				return nil, errors.New("Unterminated single quote in curl command")
+			}
+			token.WriteString(command[index+1 : index+1+end])
+			index += end + 1
+			inToken = true
+		case char == '$' && index+1 < len(command) && command[index+1] == '\'':
+			text, length, err := unquoteAnsiC(command[index+2:])
+			if err != nil {
+				return nil, err
+			}
+			token.WriteString(text)
+			index += length + 1
+			inToken = true
+		case char == '"':
+			index++
+			for ; index < len(command) && command[index] != '"'; index++ {
+				if command[index] == '\\' && index+1 < len(command) && strings.IndexByte("\"\\$`\n", command[index+1]) >= 0 {
+					index++
+					if command[index] == '\n' {
+						continue
+					}
+				}
+				token.WriteByte(command[index])
+			}
+			if index >= len(command) {
+				return nil, errors.New("Unterminated double quote in curl command")
+			}
+			inToken = true
+		default:
+			token.WriteByte(char)
+			inToken = true
+		}
+	}
+	if inToken {
+		tokens = append(tokens, token.String())
+	}
+	return tokens, nil
+}
+
+// unquoteAnsiC decodes the body of a $'...' string and returns how many bytes
+// it used, including the closing quote.
+func unquoteAnsiC(text string) (string, int, error) {
+	var builder strings.Builder
+	for index := 0; index < len(text); index++ {
+		char := text[index]
+		if char == '\'' {
+			return builder.String(), index + 1, nil
+		}
+		if char != '\\' || index+1 >= len(text) {
+			builder.WriteByte(char)
+			continue
+		}
+		index++
+		switch text[index] {
+		case 'n':
+			builder.WriteByte('\n')
+		case 't':
+			builder.WriteByte('\t')
+		case 'r':
+			builder.WriteByte('\r')
+		case 'a':
+			builder.WriteByte('\a')
+		case 'b':
+			builder.WriteByte('\b')
+		case 'e', 'E':
+			builder.WriteByte(0x1b)
+		case 'f':
+			builder.WriteByte('\f')
+		case 'v':
+			builder.WriteByte('\v')
+		case 'x', 'u', 'U':
+			digits := map[byte]int{'x': 2, 'u': 4, 'U': 8}[text[index]]
+			end := index + 1
+			for end < len(text) && end <= index+digits && strings.IndexByte("0123456789abcdefABCDEF", text[end]) >= 0 {
+				end++
+			}
+			if end == index+1 {
+				builder.WriteByte('\\')
+				builder.WriteByte(text[index])
+				continue
+			}
+			value, _ := strconv.ParseUint(text[index+1:end], 16, 32)
+			if text[index] == 'x' {
+				builder.WriteByte(byte(value))
+			} else {
+				builder.WriteRune(rune(value))
+			}
+			index = end - 1
+		case '0', '1', '2', '3', '4', '5', '6', '7':
+			end := index
+			for end < len(text) && end < index+3 && text[end] >= '0' && text[end] <= '7' {
+				end++
+			}
+			value, _ := strconv.ParseUint(text[index:end], 8, 8)
+			builder.WriteByte(byte(value))
+			index = end - 1
+		default:
+			// \\, \', \" and \? stand for themselves
+			if strings.IndexByte("\\'\"?", text[index]) < 0 {
+				builder.WriteByte('\\')
+			}
+			builder.WriteByte(text[index])
+		}
+	}
+	return "", 0, errors.New("Unterminated $' quote in curl command")
+}
+
+// splitCurlOptions expands combined short flags like -sSLk and attached values
+// like -XPOST into one option each. Arguments keep an empty name.
+func splitCurlOptions(tokens []string) ([]curlOption, []string) {
+	options := []curlOption{}
+	warnings := []string{}
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		switch {
+		case token == "--":
+			for _, argument := range tokens[i+1:] {
+				options = append(options, curlOption{value: argument})
+			}
+			return options, warnings
+		case strings.HasPrefix(token, "--"):
+			name := token
+			if strings.HasPrefix(name, "--no-") && !curlOptionsWithValue[name] && !curlIgnoredOptions[name] {
+				// --no-<option> switches a boolean option off
+				options = append(options, curlOption{name: name})
+				continue
+			}
+			if curlOptionsWithValue[name] {
+				if i+1 >= len(tokens) {
+					warnings = append(warnings, fmt.Sprint("Missing value for ", name))
+					continue
+				}
+				options = append(options, curlOption{name: name, value: tokens[i+1]})
+				i++ // Skip the value token
+				continue
+			}
+			options = append(options, curlOption{name: name})
+		case strings.HasPrefix(token, "-") && len(token) > 1:
+			for index := 1; index < len(token); index++ {
+				name, known := curlShortOptions[token[index]]
+				if !known {
+					warnings = append(warnings, fmt.Sprint("Unsupported option -", string(token[index])))
+					continue
+				}
+				if !curlOptionsWithValue[name] {
+					options = append(options, curlOption{name: name})
+					continue
+				}
+				value := token[index+1:]
+				if value == "" {
+					if i+1 >= len(tokens) {
+						warnings = append(warnings, fmt.Sprint("Missing value for -", string(token[index])))
+						break
+					}
+					value = tokens[i+1]
+					i++ // Skip the value token
+				}
+				options = append(options, curlOption{name: name, value: value})
+				break
+			}
+		default:
+			options = append(options, curlOption{value: token})
+		}
+	}
+	return options, warnings
+}
+
+func parseCurlHeader(header string) (string, string, bool) {
+	key, value, found := strings.Cut(header, ":")
+	if !found {
+		// "Name;" sends the header with an empty value
+		if strings.HasSuffix(header, ";") {
+			return strings.TrimSpace(strings.TrimSuffix(header, ";")), "", true
+		}
+		return "", "", false
+	}
+	return strings.TrimSpace(key), strings.TrimSpace(value), true
+}
+
+func encodeCurlUrlencodedData(data string) (string, bool) {
+	if strings.Contains(data, "@") {
+		name, _, _ := strings.Cut(data, "@")
+		if !strings.Contains(name, "=") {
+			return "", false
+		}
+	}
+	name, content, found := strings.Cut(data, "=")
+	if !found {
+		return url.QueryEscape(data), true
+	}
+	if name == "" {
+		return url.QueryEscape(content), true
+	}
+	return name + "=" + url.QueryEscape(content), true
+}
+
+// isFormData reports whether data is made of urlencoded key=value pairs that
+// the form body sends again unchanged, apart from how spaces are encoded.
+func isFormData(data string) bool {
+	if data == "" {
+		return false
+	}
+	for _, pair := range strings.Split(data, "&") {
+		key, value, _ := strings.Cut(pair, "=")
+		if key == "" || !isUrlencoded(key) || !isUrlencoded(value) {
+			return false
+		}
+	}
+	return true
+}
+
+func isUrlencoded(text string) bool {
+	for index := 0; index < len(text); index++ {
+		char := text[index]
+		switch {
+		case char >= 'a' && char <= 'z' || char >= 'A' && char <= 'Z' || char >= '0' && char <= '9':
+		case strings.IndexByte("-_.~+", char) >= 0:
+		case char == '%' && index+2 < len(text) && isHexDigit(text[index+1]) && isHexDigit(text[index+2]):
+			index += 2
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func isHexDigit(char byte) bool {
+	return char >= '0' && char <= '9' || char >= 'a' && char <= 'f' || char >= 'A' && char <= 'F'
+}
+
+func formatKeyValueLines(pairs []KeyValue, separator string) string {
+	text := ""
+	for _, pair := range pairs {
+		text += pair.Key
+		if pair.HasValue {
+			text += separator + pair.Value
+		}
+		text += "\n"
+	}
+	return text
+}
+
+func hasKeyValue(pairs []KeyValue, key string) bool {
+	for _, pair := range pairs {
+		if strings.EqualFold(pair.Key, key) {
+			return true
+		}
+	}
+	return false
+}
+
+func rawLanguageForContentType(contentType string) string {
+	contentType = strings.ToLower(contentType)
+	switch {
+	case strings.Contains(contentType, "ndjson"):
+		return RAW_LANGUAGE_NDJSON
+	case strings.Contains(contentType, "json"):
+		return RAW_LANGUAGE_JSON
+	case strings.Contains(contentType, "xml"):
+		return RAW_LANGUAGE_XML
+	case strings.Contains(contentType, "html"):
+		return RAW_LANGUAGE_HTML
+	case strings.HasPrefix(contentType, "text/"):
+		return RAW_LANGUAGE_TEXT
+	}
+	return ""
+}
+
+func curlSecondsTimeout(value string) (string, bool) {
+	seconds, err := strconv.ParseFloat(value, 64)
+	if err != nil || seconds < 0 {
+		return "", false
+	}
+	return strconv.FormatFloat(seconds, 'f', -1, 64) + "s", true
+}
+
+// parseCurlCommand turns a curl command line into a request. Anything that
+// cannot be carried over is reported in the returned warnings.
+func parseCurlCommand(curlCommand string) (VdatRequest, []string, error) {
+	tokens, err := tokenizeCurlCommand(strings.TrimSpace(curlCommand))
+	if err != nil {
+		return VdatRequest{}, nil, err
+	}
+	if len(tokens) == 0 || (tokens[0] != "curl" && tokens[0] != "curl.exe") {
+		return VdatRequest{}, nil, errors.New("invalid curl command format")
+	}
+	options, warnings := splitCurlOptions(tokens[1:])
+
+	var req VdatRequest
+	req.Title = TITLE_DEFAULT
+	req.SslEnabled = true
+
+	var headers []KeyValue
+	var data []string
+	var dataFile string
+	var jsonFlag bool
+	var multipartParts []string
+	var uploadFile string
+	var getFlag bool
+	var headFlag bool
+	var compressedFlag bool
+	var authType string
+	var contentTypeRemoved bool
+
+	for _, option := range options {
+		value := option.value
+		switch option.name {
+		case "":
+			switch {
+			case req.Url == "":
+				req.Url = value
+			case !strings.Contains(req.Url, "://") && strings.Contains(value, "://"):
+				// the first argument is likely the value of an option that
+				// is not known here, the one with a scheme is the url
+				warnings = append(warnings, fmt.Sprint("Argument ignored: ", req.Url))
+				req.Url = value
+			default:
+				warnings = append(warnings, fmt.Sprint("Only the first url is imported, ignored: ", value))
+			}
+		case "--url":
+			req.Url = value
+		case "--request":
+			req.RestMethod = strings.ToUpper(value)
+		case "--header":
+			key, headerValue, found := parseCurlHeader(value)
+			if strings.HasPrefix(value, "@") {
+				warnings = append(warnings, fmt.Sprint("Headers from a file are not imported: ", value))
+			} else if !found || key == "" {
+				return VdatRequest{}, nil, errors.New(fmt.Sprint("invalid header: ", value))
+			} else if headerValue == "" && !strings.HasSuffix(value, ";") && strings.EqualFold(key, "Content-Type") {
+				// vdat only sends a Content-Type the body type implies
+				contentTypeRemoved = true
+			} else if headerValue == "" && !strings.HasSuffix(value, ";") {
+				warnings = append(warnings, fmt.Sprint("Removing a default header is not supported, ignored: ", value))
+			} else {
+				headers = append(headers, KeyValue{Key: key, Value: headerValue, HasValue: true})
+			}
+		case "--data", "--data-ascii", "--data-binary":
+			if strings.HasPrefix(value, "@") {
+				if dataFile != "" || len(data) != 0 {
+					warnings = append(warnings, fmt.Sprint("Only one data file is imported, ignored: ", value))
+				} else {
+					dataFile = value[1:]
+				}
+				if option.name != "--data-binary" {
+					warnings = append(warnings, fmt.Sprint("curl strips newlines from ", value, ", vdat sends the file unchanged"))
+				}
+			} else {
+				data = append(data, value)
+			}
+		case "--data-raw":
+			data = append(data, value)
+		case "--data-urlencode":
+			encoded, ok := encodeCurlUrlencodedData(value)
+			if ok {
+				data = append(data, encoded)
+			} else {
+				warnings = append(warnings, fmt.Sprint("Data from a file is not imported: --data-urlencode ", value))
+			}
+		case "--json":
+			if strings.HasPrefix(value, "@") {
+				warnings = append(warnings, fmt.Sprint("Data from a file is not imported: --json ", value))
+			} else {
+				data = append(data, value)
+			}
+			jsonFlag = true
+		case "--form":
+			part, err := parseMultipartPart(value)
+			if err != nil {
+				return VdatRequest{}, nil, err
+			}
+			multipartParts = append(multipartParts, part.String())
+		case "--form-string":
+			name, partValue, found := strings.Cut(value, "=")
+			if !found || name == "" {
+				return VdatRequest{}, nil, errors.New(fmt.Sprint("Error with multipart entry: ", value))
+			}
+			if strings.HasPrefix(partValue, "@") || strings.HasPrefix(partValue, "<") || strings.Contains(partValue, ";") {
+				warnings = append(warnings, fmt.Sprint("--form-string value is read as -F syntax in vdat: ", value))
+			}
+			multipartParts = append(multipartParts, name+"="+partValue)
+		case "--upload-file":
+			uploadFile = value
+		case "--get":
+			getFlag = true
+		case "--head":
+			headFlag = true
+		case "--compressed":
+			compressedFlag = true
+		case "--user":
+			req.Auth.Username, req.Auth.Password, _ = strings.Cut(value, ":")
+			if authType == "" {
+				authType = AUTH_TYPE_BASIC
+			}
+		case "--basic":
+			authType = AUTH_TYPE_BASIC
+		case "--digest":
+			authType = AUTH_TYPE_DIGEST
+		case "--aws-sigv4":
+			// provider1[:provider2[:region[:service]]]
+			authType = AUTH_TYPE_AWS_SIGV4
+			providers := strings.Split(value, ":")
+			if len(providers) > 2 {
+				req.Auth.Region = providers[2]
+			}
+			if len(providers) > 3 {
+				req.Auth.Service = providers[3]
+			}
+		case "--oauth2-bearer":
+			authType = AUTH_TYPE_BEARER
+			req.Auth.Token = value
+		case "--cookie":
+			if strings.Contains(value, "=") {
+				headers = append(headers, KeyValue{Key: "Cookie", Value: value, HasValue: true})
+			} else {
+				warnings = append(warnings, fmt.Sprint("Cookies from a file are not imported: ", value))
+			}
+		case "--user-agent":
+			headers = append(headers, KeyValue{Key: "User-Agent", Value: value, HasValue: true})
+		case "--referer":
+			referer := strings.TrimSuffix(value, ";auto")
+			if referer != "" {
+				headers = append(headers, KeyValue{Key: "Referer", Value: referer, HasValue: true})
+			}
+		case "--cacert":
+			req.Tls.CaFile = value
+		case "--cert":
+			// curl takes an optional password after the last unescaped colon
+			cert := value
+			if index := strings.LastIndex(cert, ":"); index > 1 {
+				cert, req.Tls.CertPassword = cert[:index], cert[index+1:]
+			}
+			req.Tls.CertFile = cert
+		case "--key":
+			req.Tls.KeyFile = value
+		case "--pass":
+			req.Tls.CertPassword = value
+		case "--tlsv1", "--tlsv1.0":
+			req.Tls.MinVersion = "1.0"
+		case "--tlsv1.1", "--tlsv1.2", "--tlsv1.3":
+			req.Tls.MinVersion = strings.TrimPrefix(option.name, "--tlsv")
+		case "--tls-max":
+			if value != "default" {
+				req.Tls.MaxVersion = value
+			}
+		case "--insecure":
+			req.SslEnabled = false
+		case "--proxy":
+			req.Proxy.Url = value
+		case "--socks5", "--socks5-hostname":
+			req.Proxy.Url = "socks5://" + value
+		case "--proxy-user":
+			req.Proxy.Username, req.Proxy.Password, _ = strings.Cut(value, ":")
+		case "--noproxy":
+			req.Proxy.NoProxy = value
+		case "--connect-timeout":
+			timeout, ok := curlSecondsTimeout(value)
+			if ok {
+				req.Timeouts.Connect = timeout
+			} else {
+				warnings = append(warnings, fmt.Sprint("Invalid --connect-timeout: ", value))
+			}
+		case "--max-time":
+			timeout, ok := curlSecondsTimeout(value)
+			if ok {
+				req.Timeouts.Total = timeout
+			} else {
+				warnings = append(warnings, fmt.Sprint("Invalid --max-time: ", value))
+			}
+		default:
+			if !curlIgnoredOptions[option.name] {
+				warnings = append(warnings, fmt.Sprint("Unsupported option ", option.name))
+			}
+		}
+	}
+	// vdat asks for and decodes compressed responses itself, a copied
+	// Accept-Encoding header would leave the body compressed
+	contentType := ""
+	keptHeaders := []KeyValue{}
+	for _, header := range headers {
+		if compressedFlag && strings.EqualFold(header.Key, "Accept-Encoding") {
+			continue
+		}
+		if strings.EqualFold(header.Key, "Content-Type") {
+			contentType = header.Value
+		}
+		if authType == AUTH_TYPE_AWS_SIGV4 && strings.EqualFold(header.Key, "X-Amz-Security-Token") {
+			req.Auth.SessionToken = header.Value
+			continue
+		}
+		keptHeaders = append(keptHeaders, header)
+	}
+	if jsonFlag {
+		if !hasKeyValue(keptHeaders, "Accept") {
+			keptHeaders = append(keptHeaders, KeyValue{Key: "Accept", Value: "application/json", HasValue: true})
+		}
+		if contentType == "" {
+			contentType = "application/json"
+		}
+	}
+	if authType != "" {
+		req.Auth.Type = authType
+	}
+	if authType == AUTH_TYPE_AWS_SIGV4 {
+		req.Auth.AccessKey, req.Auth.SecretKey = req.Auth.Username, req.Auth.Password
+		req.Auth.Username, req.Auth.Password = "", ""
+	}
+
+	// prepare body, data joins with & like curl does
+	joinedData := strings.Join(data, "&")
+	if jsonFlag {
+		joinedData = strings.Join(data, "")
+	}
+	method := http.MethodGet
+	switch {
+	case getFlag:
+		req.BodyType = BODY_TYPE_NONE
+		if dataFile != "" {
+			warnings = append(warnings, fmt.Sprint("Data from a file cannot be sent as params with -G: @", dataFile))
+		}
+	case len(multipartParts) != 0:
+		req.BodyType = BODY_TYPE_MULTIPART
+		req.BodyContent = strings.Join(multipartParts, "\n")
+		method = http.MethodPost
+	case uploadFile != "":
+		req.BodyType = BODY_TYPE_FILE
+		req.BodyFile = uploadFile
+		method = http.MethodPut
+	case dataFile != "":
+		req.BodyType = BODY_TYPE_FILE
+		req.BodyFile = dataFile
+		method = http.MethodPost
+	case len(data) == 0:
+		req.BodyType = BODY_TYPE_NONE
+	case !contentTypeRemoved && (contentType == "" || strings.EqualFold(strings.TrimSpace(strings.Split(contentType, ";")[0]), CONTENT_TYPE_FORM)) && isFormData(joinedData):
+		req.BodyType = BODY_TYPE_FORM
+		req.BodyContent = formatKeyValueLines(readableQueryPairs(joinedData), "=")
+		method = http.MethodPost
+	default:
+		req.BodyType = BODY_TYPE_RAW
+		req.BodyContent = joinedData
+		req.RawLanguage = rawLanguageForContentType(contentType)
+		// curl sends -d data as a form even when it is not urlencoded
+		if contentType == "" && !contentTypeRemoved {
+			contentType = CONTENT_TYPE_FORM
+			keptHeaders = append(keptHeaders, KeyValue{Key: "Content-Type", Value: contentType, HasValue: true})
+		}
+		method = http.MethodPost
+	}
+
+	// a Content-Type header the body type implies anyway is left out
+	for _, header := range keptHeaders {
+		if strings.EqualFold(header.Key, "Content-Type") && strings.EqualFold(header.Value, impliedContentType(req)) {
+			continue
+		}
+		req.Headers += header.Key + "\t" + header.Value + "\n"
+	}
+
+	if headFlag {
+		method = http.MethodHead
+	}
+	if req.RestMethod == "" {
+		req.RestMethod = method
+	} else if !containsString(REST_METHODS, req.RestMethod) {
+		warnings = append(warnings, fmt.Sprint("Unsupported method ", req.RestMethod, ", using ", method))
+		req.RestMethod = method
+	}
+
+	// like curl, a url without a scheme is http
+	if req.Url == "" {
+		return VdatRequest{}, nil, errors.New("No url in curl command")
+	}
+	if !strings.Contains(req.Url, "://") {
+		req.Url = "http://" + req.Url
+	}
+	parsedURL, err := url.Parse(req.Url)
+	if err != nil {
+		return VdatRequest{}, nil, errors.New(fmt.Sprint("Error parsing URL:", req.Url))
+	}
+
+	// Move query parameters to params, keeping their order and repeated keys
+	query := parsedURL.RawQuery
+	if getFlag && joinedData != "" {
+		query += "&" + joinedData
+	}
+	req.Params = formatKeyValueLines(readableQueryPairs(query), "=")
+	parsedURL.RawQuery = ""
+	parsedURL.ForceQuery = false
+	req.Url = parsedURL.String()
+
+	if !utf8.ValidString(req.BodyContent) {
+		warnings = append(warnings, "Body is not valid UTF-8 and may not be sent exactly")
+	}
+	return req, warnings, nil
+}
+
+// shellQuote quotes text for a POSIX shell. Text with control characters
+// uses $'...' so the command keeps to one line per option.
+func shellQuote(text string) string {
+	plain := text != ""
+	control := false
+	for index := 0; index < len(text); index++ {
+		char := text[index]
+		if char < ' ' || char == 0x7f {
+			control = true
+		}
+		if !(char >= 'a' && char <= 'z' || char >= 'A' && char <= 'Z' || char >= '0' && char <= '9' || strings.IndexByte("-_./:@%+=,", char) >= 0) {
+			plain = false
+		}
+	}
+	if plain {
+		return text
+	}
+	if !control {
+		return "'" + strings.ReplaceAll(text, "'", `'\''`) + "'"
+	}
+	var builder strings.Builder
+	builder.WriteString("$'")
+	for index := 0; index < len(text); index++ {
+		char := text[index]
+		switch char {
+		case '\n':
+			builder.WriteString(`\n`)
+		case '\r':
+			builder.WriteString(`\r`)
+		case '\t':
+			builder.WriteString(`\t`)
+		case '\\', '\'':
+			builder.WriteByte('\\')
+			builder.WriteByte(char)
+		default:
+			if char < ' ' || char == 0x7f {
+				fmt.Fprintf(&builder, `\x%02x`, char)
+			} else {
+				builder.WriteByte(char)
+			}
+		}
+	}
+	builder.WriteString("'")
+	return builder.String()
+}
+
+func formatCurlSeconds(name string, value string) (string, error) {
+	timeout, err := parseTimeout(name, value)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatFloat(timeout.Seconds(), 'f', -1, 64), nil
+}
+
+// formatCurlCommand writes a request as a curl command, one option per line,
+// that parseCurlCommand reads back into an equivalent request. The request
+// must already have its environment applied.
+func formatCurlCommand(vdatRequest VdatRequest, now time.Time) (string, error) {
+	urlText, err := substitutePathParams(vdatRequest.Url, vdatRequest.PathParams)
+	if err != nil {
+		return "", err
+	}
+	urlText, err = buildRequestUrl(urlText, vdatRequest.Params)
+	if err != nil {
+		return "", err
+	}
+	urlText, err = addAuthQuery(urlText, vdatRequest.Auth)
+	if err != nil {
+		return "", err
+	}
+
+	lines := []string{}
+	add := func(option string, value string) {
+		lines = append(lines, option+" "+shellQuote(value))
+	}
+	addHeader := func(name string, value string) {
+		if value == "" {
+			add("-H", name+";")
+		} else {
+			add("-H", name+": "+value)
+		}
+	}
+
+	// headers, an empty value is written as "Name;" like curl expects
+	for _, pair := range parseKeyValueLines(vdatRequest.Headers, "\t") {
+		if pair.HasValue {
+			addHeader(strings.TrimSpace(pair.Key), strings.TrimSpace(pair.Value))
+		}
+	}
+
+	// auth, left out where a header typed by hand replaces it
+	auth := vdatRequest.Auth
+	manualAuthorization := hasHeader(vdatRequest.Headers, "Authorization")
+	switch auth.Type {
+	case AUTH_TYPE_BASIC, AUTH_TYPE_DIGEST:
+		if !manualAuthorization {
+			if auth.Type == AUTH_TYPE_DIGEST {
+				lines = append(lines, "--digest")
+			}
+			add("-u", auth.Username+":"+auth.Password)
+		}
+	case AUTH_TYPE_BEARER, AUTH_TYPE_OAUTH2:
+		if auth.Type == AUTH_TYPE_OAUTH2 && auth.Token == "" {
+			return "", errors.New(NO_OAUTH2_TOKEN_TEXT)
+		}
+		if !manualAuthorization {
+			add("--oauth2-bearer", auth.Token)
+		}
+	case AUTH_TYPE_API_KEY:
+		key := strings.TrimSpace(auth.Key)
+		if auth.In != API_KEY_IN_QUERY && !hasHeader(vdatRequest.Headers, key) {
+			addHeader(key, auth.Value)
+		}
+	case AUTH_TYPE_AWS_SIGV4:
+		if !manualAuthorization {
+			add("--aws-sigv4", "aws:amz:"+auth.Region+":"+auth.Service)
+			add("-u", auth.AccessKey+":"+auth.SecretKey)
+			if auth.SessionToken != "" {
+				addHeader("X-Amz-Security-Token", auth.SessionToken)
+			}
+		}
+	case AUTH_TYPE_HMAC:
+		// curl cannot sign, so the signature for this body is written out
+		name := hmacHeaderName(auth)
+		if !hasHeader(vdatRequest.Headers, name) {
+			req, err := buildHttpRequest(vdatRequest)
+			if err != nil {
+				return "", err
+			}
+			err = signRequest(req, vdatRequest, now)
+			if req.Body != nil {
+				req.Body.Close()
+			}
+			if err != nil {
+				return "", err
+			}
+			if auth.HmacSigns == HMAC_SIGNS_REQUEST && !hasHeader(vdatRequest.Headers, "Date") {
+				addHeader("Date", req.Header.Get("Date"))
+			}
+			addHeader(name, req.Header.Get(name))
+		}
+	}
+
+	// body, curl sends any data as a form unless told otherwise
+	hasBody := false
+	manualContentType := hasHeader(vdatRequest.Headers, "Content-Type")
+	switch vdatRequest.BodyType {
+	case BODY_TYPE_RAW:
+		hasBody = true
+		contentType := rawContentType(vdatRequest.RawLanguage)
+		if !manualContentType && contentType == "" {
+			// "Content-Type:" stops curl sending its form default
+			add("-H", "Content-Type:")
+		} else if !manualContentType {
+			addHeader("Content-Type", contentType)
+		}
+		add("--data-raw", vdatRequest.BodyContent)
+	case BODY_TYPE_FORM:
+		for _, pair := range parseKeyValueLines(vdatRequest.BodyContent, "=") {
+			encoded, err := encodeKeyValues([]KeyValue{pair}, "body")
+			if err != nil {
+				return "", err
+			}
+			add("-d", encoded)
+			hasBody = true
+		}
+	case BODY_TYPE_MULTIPART:
+		parts, err := parseMultipartParts(vdatRequest.BodyContent)
+		if err != nil {
+			return "", err
+		}
+		for _, part := range parts {
+			if part.IsFile || part.FromFile {
+				part.Value, err = resolveBodyFilePath(part.Value, vdatRequest.Path)
+				if err != nil {
+					return "", err
+				}
+			}
+			add("-F", part.String())
+			hasBody = true
+		}
+	case BODY_TYPE_FILE:
+		path, err := resolveBodyFilePath(vdatRequest.BodyFile, vdatRequest.Path)
+		if err != nil {
+			return "", err
+		}
+		if !manualContentType {
+			addHeader("Content-Type", bodyFileContentType(path))
+		}
+		add("--data-binary", "@"+path)
+		hasBody = true
+	}
+
+	// tls, with the paths resolved so the command works from any directory
+	if !vdatRequest.SslEnabled {
+		lines = append(lines, "-k")
+	}
+	settings := vdatRequest.Tls
+	for _, file := range []struct {
+		option string
+		path   string
+	}{{"--cacert", settings.CaFile}, {"--cert", settings.CertFile}, {"--key", settings.KeyFile}} {
+		if file.path == "" {
+			continue
+		}
+		path, err := resolveBodyFilePath(file.path, vdatRequest.Path)
+		if err != nil {
+			return "", err
+		}
+		add(file.option, path)
+	}
+	if settings.CertPassword != "" {
+		add("--pass", settings.CertPassword)
+	}
+	if settings.MinVersion != "" && settings.MinVersion != TLS_VERSION_DEFAULT {
+		lines = append(lines, "--tlsv"+settings.MinVersion)
+	}
+	if settings.MaxVersion != "" && settings.MaxVersion != TLS_VERSION_DEFAULT {
+		add("--tls-max", settings.MaxVersion)
+	}
+
+	proxy := vdatRequest.Proxy
+	if proxy.Url != "" {
+		add("-x", proxy.Url)
+		if proxy.Username != "" {
+			add("-U", proxy.Username+":"+proxy.Password)
+		}
+	}
+	if proxy.NoProxy != "" {
+		add("--noproxy", proxy.NoProxy)
+	}
+
+	if vdatRequest.Timeouts.Connect != "" {
+		seconds, err := formatCurlSeconds("connect", vdatRequest.Timeouts.Connect)
+		if err != nil {
+			return "", err
+		}
+		add("--connect-timeout", seconds)
+	}
+	if vdatRequest.Timeouts.Total != "" {
+		seconds, err := formatCurlSeconds("total", vdatRequest.Timeouts.Total)
+		if err != nil {
+			return "", err
+		}
+		add("-m", seconds)
+	}
+
+	// the method goes first, and only when curl would not pick it anyway
+	impliedMethod := http.MethodGet
+	if hasBody {
+		impliedMethod = http.MethodPost
+	}
+	if vdatRequest.RestMethod == http.MethodHead && !hasBody {
+		lines = append([]string{"-I"}, lines...)
+	} else if vdatRequest.RestMethod != impliedMethod {
+		lines = append([]string{"-X " + shellQuote(vdatRequest.RestMethod)}, lines...)
+	}
+
+	command := "curl " + shellQuote(urlText)
+	for _, line := range lines {
+		command += " \\\n  " + line
+	}
+	return command, nil
+}
+
+// prepareExportRequest applies the environment, the host TLS settings and
+// the cached OAuth 2.0 token, the way sending would.
+func prepareExportRequest(vdatRequest VdatRequest, environment VdatEnvironment) (VdatRequest, error) {
+	vdatRequest, err := applyEnvironment(vdatRequest, environment)
+	if err != nil {
+		return vdatRequest, err
+	}
+	vdatRequest.Tls, err = loadRequestTls(vdatRequest)
+	if err != nil {
+		return vdatRequest, err
+	}
+	if vdatRequest.Auth.Type == AUTH_TYPE_OAUTH2 {
+		token, found := loadCachedToken(environment.Name, vdatRequest.Auth)
+		if found && token.valid() {
+			vdatRequest.Auth.Token = token.AccessToken
+		}
+	}
+	return vdatRequest, nil
+}
+
+func exportCurlCommand(vdatRequest VdatRequest, environment VdatEnvironment) (string, error) {
+	vdatRequest, err := prepareExportRequest(vdatRequest, environment)
+	if err != nil {
+		return "", err
+	}
+	return formatCurlCommand(vdatRequest, time.Now())
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type sentRequest struct {
+	method string
+	url    string
+	header http.Header
+	body   string
+}
+
+// send builds and signs a request the way SEND does, without sending it.
+func send(t *testing.T, vdatRequest VdatRequest, now time.Time) sentRequest {
+	t.Helper()
+	req, err := buildHttpRequest(vdatRequest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = signRequest(req, vdatRequest, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := ""
+	if req.Body != nil {
+		content, err := io.ReadAll(req.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body = string(content)
+	}
+	header := req.Header.Clone()
+	// multipart boundaries are random, the parts are compared instead
+	if strings.HasPrefix(header.Get("Content-Type"), "multipart/form-data") {
+		header.Set("Content-Type", "multipart/form-data")
+		body = ""
+	}
+	return sentRequest{method: req.Method, url: req.URL.String(), header: header, body: body}
+}
+
+func TestCurlRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	upload := filepath.Join(dir, "upload.json")
+	err := os.WriteFile(upload, []byte(`{"a": 1}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	base := VdatRequest{
+		Url:        "https://api.example.com/items/:id",
+		PathParams: "id=7",
+		Params:     "page=1\nq=a b\n#off=1",
+		Headers:    "Accept\tapplication/json",
+		RestMethod: "GET",
+		BodyType:   BODY_TYPE_NONE,
+		SslEnabled: true,
+	}
+	with := func(change func(*VdatRequest)) VdatRequest {
+		vdatRequest := base
+		change(&vdatRequest)
+		return vdatRequest
+	}
+	tests := []struct {
+		name    string
+		request VdatRequest
+	}{
+		{"none", base},
+		{"head", with(func(r *VdatRequest) { r.RestMethod = "HEAD" })},
+		{"delete", with(func(r *VdatRequest) { r.RestMethod = "DELETE" })},
+		{"raw json", with(func(r *VdatRequest) {
+			r.RestMethod, r.BodyType, r.RawLanguage, r.BodyContent = "POST", BODY_TYPE_RAW, RAW_LANGUAGE_JSON, `{"name": "it's"}`
+		})},
+		{"raw xml put", with(func(r *VdatRequest) {
+			r.RestMethod, r.BodyType, r.RawLanguage, r.BodyContent = "PUT", BODY_TYPE_RAW, RAW_LANGUAGE_XML, "<a>\n\t<b/>\n</a>"
+		})},
+		{"raw without language", with(func(r *VdatRequest) {
+			r.RestMethod, r.BodyType, r.BodyContent = "POST", BODY_TYPE_RAW, "plain text"
+		})},
+		{"raw without language that looks like a form", with(func(r *VdatRequest) {
+			r.RestMethod, r.BodyType, r.BodyContent = "POST", BODY_TYPE_RAW, "a=b"
+		})},
+		{"form", with(func(r *VdatRequest) {
+			r.RestMethod, r.BodyType, r.BodyContent = "POST", BODY_TYPE_FORM, "name=a b\nsymbols=%26%3D\n#off=1"
+		})},
+		{"multipart", with(func(r *VdatRequest) {
+			r.RestMethod, r.BodyType, r.BodyContent = "POST", BODY_TYPE_MULTIPART, "name=value\nfile=@"+upload+";type=application/json"
+		})},
+		{"file", with(func(r *VdatRequest) {
+			r.RestMethod, r.BodyType, r.BodyFile = "PUT", BODY_TYPE_FILE, upload
+		})},
+		{"basic", with(func(r *VdatRequest) {
+			r.Auth = VdatAuth{Type: AUTH_TYPE_BASIC, Username: "user", Password: "p:ss"}
+		})},
+		{"bearer", with(func(r *VdatRequest) { r.Auth = VdatAuth{Type: AUTH_TYPE_BEARER, Token: "abc"} })},
+		{"api key header", with(func(r *VdatRequest) {
+			r.Auth = VdatAuth{Type: AUTH_TYPE_API_KEY, Key: "X-Key", Value: "secret", In: API_KEY_IN_HEADER}
+		})},
+		{"api key query", with(func(r *VdatRequest) {
+			r.Auth = VdatAuth{Type: AUTH_TYPE_API_KEY, Key: "key", Value: "s e", In: API_KEY_IN_QUERY}
+		})},
+		{"oauth2", with(func(r *VdatRequest) {
+			r.Auth = VdatAuth{Type: AUTH_TYPE_OAUTH2, Grant: OAUTH2_GRANT_CLIENT_CREDENTIALS, Token: "token"}
+		})},
+		{"sigv4", with(func(r *VdatRequest) {
+			r.RestMethod, r.BodyType, r.RawLanguage, r.BodyContent = "POST", BODY_TYPE_RAW, RAW_LANGUAGE_JSON, `{}`
+			r.Auth = VdatAuth{Type: AUTH_TYPE_AWS_SIGV4, AccessKey: "AK", SecretKey: "SK", Region: "eu-west-1", Service: "execute-api", SessionToken: "ST"}
+		})},
+		{"hmac", with(func(r *VdatRequest) {
+			r.RestMethod, r.BodyType, r.RawLanguage, r.BodyContent = "POST", BODY_TYPE_RAW, RAW_LANGUAGE_JSON, `{}`
+			r.Auth = VdatAuth{Type: AUTH_TYPE_HMAC, HmacKey: "key", HmacAlgorithm: HMAC_ALGORITHM_SHA256}
+		})},
+		{"insecure", with(func(r *VdatRequest) { r.SslEnabled = false })},
+		{"ca file", with(func(r *VdatRequest) { r.Tls.CaFile = "/certs/ca.pem" })},
+		{"client certificate", with(func(r *VdatRequest) {
+			r.Tls.CertFile, r.Tls.KeyFile, r.Tls.CertPassword = "/certs/client.pem", "/certs/client.key", "pass word"
+		})},
+		{"tls versions", with(func(r *VdatRequest) { r.Tls.MinVersion, r.Tls.MaxVersion = "1.2", "1.3" })},
+		{"proxy", with(func(r *VdatRequest) { r.Proxy.Url = "http://proxy.local:3128" })},
+		{"proxy credentials", with(func(r *VdatRequest) {
+			r.Proxy = VdatProxy{Url: "socks5://proxy.local:1080", Username: "me", Password: "p w"}
+		})},
+		{"no proxy", with(func(r *VdatRequest) {
+			r.Proxy = VdatProxy{Url: "http://proxy.local:3128", NoProxy: "localhost,.internal"}
+		})},
+		{"timeouts", with(func(r *VdatRequest) { r.Timeouts.Connect, r.Timeouts.Total = "2.5s", "30s" })},
+	}
+	for _, test := range tests {
+		command, err := formatCurlCommand(test.request, now)
+		if err != nil {
+			t.Errorf("%s: format: %v", test.name, err)
+			continue
+		}
+		parsed, warnings, err := parseCurlCommand(command)
+		if err != nil {
+			t.Errorf("%s: parse: %v\n%s", test.name, err, command)
+			continue
+		}
+		if len(warnings) != 0 {
+			t.Errorf("%s: warnings %q\n%s", test.name, warnings, command)
+		}
+
+		want := send(t, test.request, now)
+		got := send(t, parsed, now)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: sent\n%+v\nwant\n%+v\n%s", test.name, got, want, command)
+		}
+		if test.request.BodyType == BODY_TYPE_MULTIPART && parsed.BodyContent != test.request.BodyContent {
+			t.Errorf("%s: parts %q, want %q", test.name, parsed.BodyContent, test.request.BodyContent)
+		}
+		if test.request.Auth.Type == AUTH_TYPE_DIGEST && parsed.Auth != test.request.Auth {
+			t.Errorf("%s: auth %+v, want %+v", test.name, parsed.Auth, test.request.Auth)
+		}
+		if parsed.SslEnabled != test.request.SslEnabled || parsed.Tls != test.request.Tls || parsed.Proxy != test.request.Proxy || parsed.Timeouts != test.request.Timeouts {
+			t.Errorf("%s: settings %v %+v %+v %+v\n%s", test.name, parsed.SslEnabled, parsed.Tls, parsed.Proxy, parsed.Timeouts, command)
+		}
+	}
+}
+
+func TestCurlRoundTripDigest(t *testing.T) {
+	vdatRequest := VdatRequest{
+		Url:        "https://example.com/",
+		RestMethod: "GET",
+		BodyType:   BODY_TYPE_NONE,
+		SslEnabled: true,
+		Auth:       VdatAuth{Type: AUTH_TYPE_DIGEST, Username: "user", Password: "pass"},
+	}
+	command, err := formatCurlCommand(vdatRequest, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, _, err := parseCurlCommand(command)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Auth != vdatRequest.Auth {
+		t.Errorf("auth %+v, want %+v", parsed.Auth, vdatRequest.Auth)
+	}
+}
+
+func TestParseCurlCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		command  string
+		want     VdatRequest
+		warnings []string
+	}{
+		{
+			name:    "continuations",
+			command: "curl -X POST \\\n  -H 'Accept: application/json' \\\r\n  https://example.com/a",
+			want:    VdatRequest{RestMethod: "POST", Url: "https://example.com/a", Headers: "Accept\tapplication/json\n", BodyType: BODY_TYPE_NONE, SslEnabled: true},
+		},
+		{
+			name:    "ansi c quoting",
+			command: `curl $'https://example.com/it\'s' -H $'X-Tab: a\tb'`,
+			want:    VdatRequest{RestMethod: "GET", Url: "https://example.com/it's", Headers: "X-Tab\ta\tb\n", BodyType: BODY_TYPE_NONE, SslEnabled: true},
+		},
+		{
+			name:    "combined short flags",
+			command: "curl -sSLkXPUT -dname=x https://example.com/",
+			want:    VdatRequest{RestMethod: "PUT", Url: "https://example.com/", BodyType: BODY_TYPE_FORM, BodyContent: "name=x\n"},
+		},
+		{
+			name:    "get with data",
+			command: "curl -G -d q=a+b -d page=2 https://example.com/search",
+			want:    VdatRequest{RestMethod: "GET", Url: "https://example.com/search", Params: "q=a b\npage=2\n", BodyType: BODY_TYPE_NONE, SslEnabled: true},
+		},
+		{
+			name:    "json",
+			command: `curl --json '{"a":1}' https://example.com/j`,
+			want:    VdatRequest{RestMethod: "POST", Url: "https://example.com/j", Headers: "Accept\tapplication/json\n", BodyType: BODY_TYPE_RAW, RawLanguage: RAW_LANGUAGE_JSON, BodyContent: `{"a":1}`, SslEnabled: true},
+		},
+		{
+			name:    "known option with a value",
+			command: "curl --limit-rate 100K https://example.com/",
+			want:    VdatRequest{RestMethod: "GET", Url: "https://example.com/", BodyType: BODY_TYPE_NONE, SslEnabled: true},
+		},
+		{
+			name:     "unknown option with a value",
+			command:  "curl --frobnicate 100K https://example.com/",
+			want:     VdatRequest{RestMethod: "GET", Url: "https://example.com/", BodyType: BODY_TYPE_NONE, SslEnabled: true},
+			warnings: []string{"Unsupported option --frobnicate", "Argument ignored: 100K"},
+		},
+		{
+			name:    "warnings",
+			command: "curl -Z --frobnicate -H @headers.txt https://a.example/ https://b.example/",
+			want:    VdatRequest{RestMethod: "GET", Url: "https://a.example/", BodyType: BODY_TYPE_NONE, SslEnabled: true},
+			warnings: []string{
+				"Unsupported option -Z",
+				"Unsupported option --frobnicate",
+				"Headers from a file are not imported: @headers.txt",
+				"Only the first url is imported, ignored: https://b.example/",
+			},
+		},
+	}
+	for _, test := range tests {
+		got, warnings, err := parseCurlCommand(test.command)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		test.want.Title = TITLE_DEFAULT
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got\n%+v\nwant\n%+v", test.name, got, test.want)
+		}
+		if len(warnings) != 0 || len(test.warnings) != 0 {
+			if !reflect.DeepEqual(warnings, test.warnings) {
+				t.Errorf("%s: warnings %q, want %q", test.name, warnings, test.warnings)
+			}
+		}
+	}
+}
+
+func TestParseCurlCommandErrors(t *testing.T) {
+	for _, command := range []string{"", "wget https://example.com/", "curl -H", "curl 'https://example.com/", "curl -H 'no colon' https://example.com/"} {
+		_, _, err := parseCurlCommand(command)
+		if err == nil {
+			t.Errorf("parseCurlCommand(%q) gave no error", command)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+type KeyValue struct {
+	Key      string
+	Value    string
+	HasValue bool
+}
+
+func parseKeyValueLines(text string, separator string) []KeyValue {
+	pairs := []KeyValue{}
+	for _, line := range strings.Split(text, "\n") {
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		key, value, found := strings.Cut(line, separator)
+		pairs = append(pairs, KeyValue{Key: key, Value: value, HasValue: found})
+	}
+	return pairs
+}
+
+// decodePercent lets already encoded %XX sequences through unchanged by
+// decoding them before they are encoded again. Text that is not valid
+// percent-encoding, like a bare "100%", is taken literally.
+func decodePercent(text string) string {
+	decoded, err := url.PathUnescape(text)
+	if err != nil {
+		return text
+	}
+	return decoded
+}
+
+func encodeKeyValues(pairs []KeyValue, entryName string) (string, error) {
+	encoded := []string{}
+	for _, pair := range pairs {
+		if pair.Key == "" {
+			return "", errors.New(fmt.Sprint("Error with ", entryName, " entry: ", pair.Key, "=", pair.Value))
+		}
+		text := url.QueryEscape(decodePercent(pair.Key))
+		if pair.HasValue {
+			text += "=" + url.QueryEscape(decodePercent(pair.Value))
+		}
+		encoded = append(encoded, text)
+	}
+	return strings.Join(encoded, "&"), nil
+}
+
+func buildRequestUrl(urlText string, paramsText string) (string, error) {
+	parsedUrl, err := url.Parse(urlText)
+	if err != nil {
+		return "", err
+	}
+	query, err := encodeKeyValues(parseKeyValueLines(paramsText, "="), "param")
+	if err != nil {
+		return "", err
+	}
+	if query != "" {
+		if parsedUrl.RawQuery != "" {
+			parsedUrl.RawQuery += "&" + query
+		} else {
+			parsedUrl.RawQuery = query
+		}
+	}
+	return parsedUrl.String(), nil
+}
+
+func isTokenRune(r rune) bool {
+	if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+		return true
+	}
+	return strings.ContainsRune("!#$%&'*+-.^_`|~", r)
+}
+
+func validHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if !isTokenRune(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func validHeaderValue(value string) bool {
+	for _, r := range value {
+		if r == 0x7f || r < ' ' && r != '\t' {
+			return false
+		}
+	}
+	return true
+}
+
+var queryEscaper = strings.NewReplacer("&", "%26", "#", "%23")
+
+func parseQueryPairs(query string) []KeyValue {
+	pairs := []KeyValue{}
+	for _, part := range strings.Split(query, "&") {
+		if part == "" {
+			continue
+		}
+		key, value, found := strings.Cut(part, "=")
+		pairs = append(pairs, KeyValue{Key: key, Value: value, HasValue: found})
+	}
+	return pairs
+}
+
+// readableQueryPairs splits a urlencoded query into pairs for param rows,
+// decoding each key and value unless the decoded text would be read back
+// differently when the row is encoded again.
+func readableQueryPairs(query string) []KeyValue {
+	pairs := parseQueryPairs(query)
+	for index, pair := range pairs {
+		pairs[index].Key = readableQueryText(pair.Key, "=")
+		pairs[index].Value = readableQueryText(pair.Value, "")
+	}
+	return pairs
+}
+
+// readableQueryRows does the same for rows holding query text as written
+// in a url.
+func readableQueryRows(rows []KeyValueRow) []KeyValueRow {
+	for index, row := range rows {
+		rows[index].Key = readableQueryText(row.Key, "=")
+		rows[index].Value = readableQueryText(row.Value, "")
+	}
+	return rows
+}
+
+// readableQueryText decodes with query semantics, a "+" is a space. Rows
+// are decoded as paths when sent, so a kept "+" becomes %20 instead.
+func readableQueryText(text string, reserved string) string {
+	text = strings.ReplaceAll(text, "+", "%20")
+	decoded, err := url.QueryUnescape(text)
+	if err != nil || strings.ContainsAny(decoded, "%\n\r"+reserved) || strings.HasPrefix(decoded, "#") {
+		return text
+	}
+	return decoded
+}
+
+// formatQuery writes the enabled rows as they are typed, escaping only what
+// would split a pair. Encoding happens when the request is sent.
+func formatQuery(rows []KeyValueRow) string {
+	parts := []string{}
+	for _, row := range rows {
+		if !row.Enabled || row.Key == "" {
+			continue
+		}
+		part := queryEscaper.Replace(row.Key)
+		if row.HasValue || row.Value != "" {
+			part += "=" + queryEscaper.Replace(row.Value)
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, "&")
+}
+
+// mergeQueryRows replaces the enabled rows with the pairs from a query,
+// keeping disabled rows, descriptions and the position of each row.
+func mergeQueryRows(rows []KeyValueRow, pairs []KeyValue) []KeyValueRow {
+	merged := []KeyValueRow{}
+	next := 0
+	for _, row := range rows {
+		if !row.Enabled || row.Key == "" {
+			merged = append(merged, row)
+			continue
+		}
+		if next < len(pairs) {
+			row.KeyValue = pairs[next]
+			merged = append(merged, row)
+			next++
+		}
+	}
+	for ; next < len(pairs); next++ {
+		merged = append(merged, KeyValueRow{KeyValue: pairs[next], Enabled: true})
+	}
+	return merged
+}
+
+// splitUrlQuery drops the fragment, which is never sent, before splitting off
+// the query so "#" in a fragment does not end up in the last value.
+func splitUrlQuery(text string) (string, string) {
+	text, _, _ = strings.Cut(text, "#")
+	base, query, _ := strings.Cut(text, "?")
+	return base, query
+}
+
+func urlBase(text string) string {
+	base, _ := splitUrlQuery(text)
+	return base
+}
+
+func composeUrl(base string, rows []KeyValueRow) string {
+	query := formatQuery(rows)
+	if query == "" {
+		return base
+	}
+	return base + "?" + query
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestSplitUrlQuery(t *testing.T) {
+	tests := []struct {
+		text  string
+		base  string
+		query string
+	}{
+		{"https://example.com/a", "https://example.com/a", ""},
+		{"https://example.com/a?q=1", "https://example.com/a", "q=1"},
+		{"https://example.com/a?q=1#top", "https://example.com/a", "q=1"},
+		{"https://example.com/a#top?q=1", "https://example.com/a", ""},
+		{"https://example.com/a?q=%23#top", "https://example.com/a", "q=%23"},
+	}
+	for _, test := range tests {
+		base, query := splitUrlQuery(test.text)
+		if base != test.base || query != test.query {
+			t.Errorf("splitUrlQuery(%q) = %q, %q, want %q, %q", test.text, base, query, test.base, test.query)
+		}
+		if urlBase(test.text) != test.base {
+			t.Errorf("urlBase(%q) = %q, want %q", test.text, urlBase(test.text), test.base)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var variablePattern = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_.\-]+)\s*\}\}`)
+
+type EnvironmentCallback func() (VdatEnvironment, error)
+type VdatEnvironment struct {
+	Name      string `json:"Name"`
+	Variables string `json:"Variables"`
+}
+
+func getEnvironmentsDir() (string, error) {
+	vdatDir, err := getVdatDir()
+	if err != nil {
+		return "", err
+	}
+	environmentsDir := filepath.Join(vdatDir, ENVIRONMENTS_DIR)
+	err = os.MkdirAll(environmentsDir, os.ModePerm)
+	return environmentsDir, err
+}
+
+func listEnvironments() ([]string, error) {
+	environmentsDir, err := getEnvironmentsDir()
+	if err != nil {
+		return nil, err
+	}
+	files, err := os.ReadDir(environmentsDir)
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, file := range files {
+		if !file.IsDir() {
+			names = append(names, file.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// validateEnvironmentName keeps an environment to a single visible file in
+// the environments directory.
+func validateEnvironmentName(name string) error {
+	if name == "" || name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+		return errors.New(fmt.Sprint("Invalid environment name: ", name))
+	}
+	return nil
+}
+
+func loadEnvironment(name string) (VdatEnvironment, error) {
+	if name == "" {
+		return VdatEnvironment{}, nil
+	}
+	err := validateEnvironmentName(name)
+	if err != nil {
+		return VdatEnvironment{}, err
+	}
+	environmentsDir, err := getEnvironmentsDir()
+	if err != nil {
+		return VdatEnvironment{}, err
+	}
+	file, err := os.Open(filepath.Join(environmentsDir, name))
+	if err != nil {
+		return VdatEnvironment{}, err
+	}
+	defer file.Close()
+
+	environment := VdatEnvironment{}
+	decoder := json.NewDecoder(file)
+	err = decoder.Decode(&environment)
+	environment.Name = name
+	return environment, err
+}
+
+func saveEnvironment(environment VdatEnvironment) error {
+	err := validateEnvironmentName(environment.Name)
+	if err != nil {
+		return err
+	}
+	environmentsDir, err := getEnvironmentsDir()
+	if err != nil {
+		return err
+	}
+	file, err := os.Create(filepath.Join(environmentsDir, environment.Name))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	return encoder.Encode(environment)
+}
+
+func (environment VdatEnvironment) variables() (map[string]string, error) {
+	variables := make(map[string]string)
+	for _, line := range strings.Split(environment.Variables, "\n") {
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		key, value, found := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !found || key == "" {
+			return nil, errors.New(fmt.Sprint("Error with variable entry in ", environment.Name, ": ", line))
+		}
+		variables[key] = value
+	}
+	return variables, nil
+}
+
+func substituteVariables(text string, variables map[string]string, unresolved map[string]bool) string {
+	return variablePattern.ReplaceAllStringFunc(text, func(match string) string {
+		name := variablePattern.FindStringSubmatch(match)[1]
+		value, found := variables[name]
+		if !found {
+			unresolved[name] = true
+			return match
+		}
+		return value
+	})
+}
+
+func applyEnvironment(vdatRequest VdatRequest, environment VdatEnvironment) (VdatRequest, error) {
+	variables, err := environment.variables()
+	if err != nil {
+		return vdatRequest, err
+	}
+
+	unresolved := make(map[string]bool)
+	vdatRequest.Url = substituteVariables(vdatRequest.Url, variables, unresolved)
+	vdatRequest.Params = substituteVariables(vdatRequest.Params, variables, unresolved)
+	vdatRequest.PathParams = substituteVariables(vdatRequest.PathParams, variables, unresolved)
+	vdatRequest.Headers = substituteVariables(vdatRequest.Headers, variables, unresolved)
+	vdatRequest.BodyContent = substituteVariables(vdatRequest.BodyContent, variables, unresolved)
+	vdatRequest.BodyFile = substituteVariables(vdatRequest.BodyFile, variables, unresolved)
+	vdatRequest.Auth.Username = substituteVariables(vdatRequest.Auth.Username, variables, unresolved)
+	vdatRequest.Auth.Password = substituteVariables(vdatRequest.Auth.Password, variables, unresolved)
+	vdatRequest.Auth.Token = substituteVariables(vdatRequest.Auth.Token, variables, unresolved)
+	vdatRequest.Auth.Key = substituteVariables(vdatRequest.Auth.Key, variables, unresolved)
+	vdatRequest.Auth.Value = substituteVariables(vdatRequest.Auth.Value, variables, unresolved)
+	vdatRequest.Auth.TokenUrl = substituteVariables(vdatRequest.Auth.TokenUrl, variables, unresolved)
+	vdatRequest.Auth.AuthUrl = substituteVariables(vdatRequest.Auth.AuthUrl, variables, unresolved)
+	vdatRequest.Auth.ClientId = substituteVariables(vdatRequest.Auth.ClientId, variables, unresolved)
+	vdatRequest.Auth.ClientSecret = substituteVariables(vdatRequest.Auth.ClientSecret, variables, unresolved)
+	vdatRequest.Auth.Scope = substituteVariables(vdatRequest.Auth.Scope, variables, unresolved)
+	vdatRequest.Auth.RefreshToken = substituteVariables(vdatRequest.Auth.RefreshToken, variables, unresolved)
+	vdatRequest.Auth.Region = substituteVariables(vdatRequest.Auth.Region, variables, unresolved)
+	vdatRequest.Auth.Service = substituteVariables(vdatRequest.Auth.Service, variables, unresolved)
+	vdatRequest.Auth.AccessKey = substituteVariables(vdatRequest.Auth.AccessKey, variables, unresolved)
+	vdatRequest.Auth.SecretKey = substituteVariables(vdatRequest.Auth.SecretKey, variables, unresolved)
+	vdatRequest.Auth.SessionToken = substituteVariables(vdatRequest.Auth.SessionToken, variables, unresolved)
+	vdatRequest.Auth.HmacKey = substituteVariables(vdatRequest.Auth.HmacKey, variables, unresolved)
+	vdatRequest.Auth.HmacHeader = substituteVariables(vdatRequest.Auth.HmacHeader, variables, unresolved)
+	vdatRequest.Proxy.Url = substituteVariables(vdatRequest.Proxy.Url, variables, unresolved)
+	vdatRequest.Proxy.Username = substituteVariables(vdatRequest.Proxy.Username, variables, unresolved)
+	vdatRequest.Proxy.Password = substituteVariables(vdatRequest.Proxy.Password, variables, unresolved)
+	vdatRequest.Proxy.NoProxy = substituteVariables(vdatRequest.Proxy.NoProxy, variables, unresolved)
+	vdatRequest.Tls.CaFile = substituteVariables(vdatRequest.Tls.CaFile, variables, unresolved)
+	vdatRequest.Tls.CertFile = substituteVariables(vdatRequest.Tls.CertFile, variables, unresolved)
+	vdatRequest.Tls.KeyFile = substituteVariables(vdatRequest.Tls.KeyFile, variables, unresolved)
+	vdatRequest.Tls.CertPassword = substituteVariables(vdatRequest.Tls.CertPassword, variables, unresolved)
+	vdatRequest.Tls.ServerName = substituteVariables(vdatRequest.Tls.ServerName, variables, unresolved)
+	vdatRequest.Tls.MinVersion = substituteVariables(vdatRequest.Tls.MinVersion, variables, unresolved)
+	vdatRequest.Tls.MaxVersion = substituteVariables(vdatRequest.Tls.MaxVersion, variables, unresolved)
+
+	if len(unresolved) != 0 {
+		names := []string{}
+		for name := range unresolved {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if environment.Name == "" {
+			return vdatRequest, errors.New(fmt.Sprint("Unresolved variables (no environment selected): ", strings.Join(names, ", ")))
+		}
+		return vdatRequest, errors.New(fmt.Sprint("Unresolved variables in environment ", environment.Name, ": ", strings.Join(names, ", ")))
+	}
+	return vdatRequest, nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestApplyEnvironmentTls(t *testing.T) {
+	environment := VdatEnvironment{Name: "dev", Variables: "certs=/etc/certs\nhost=api.internal\npass=secret\nmin=1.2"}
+	vdatRequest := VdatRequest{Tls: VdatTls{
+		CaFile:       "{{certs}}/ca.pem",
+		CertFile:     "{{certs}}/client.pem",
+		KeyFile:      "{{certs}}/client.key",
+		CertPassword: "{{pass}}",
+		ServerName:   "{{host}}",
+		MinVersion:   "{{min}}",
+		MaxVersion:   "{{min}}",
+	}}
+	got, err := applyEnvironment(vdatRequest, environment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := VdatTls{
+		CaFile:       "/etc/certs/ca.pem",
+		CertFile:     "/etc/certs/client.pem",
+		KeyFile:      "/etc/certs/client.key",
+		CertPassword: "secret",
+		ServerName:   "api.internal",
+		MinVersion:   "1.2",
+		MaxVersion:   "1.2",
+	}
+	if got.Tls != want {
+		t.Errorf("Tls = %+v, want %+v", got.Tls, want)
+	}
+
+	vdatRequest.Tls = VdatTls{CaFile: "{{missing}}/ca.pem"}
+	_, err = applyEnvironment(vdatRequest, environment)
+	if err == nil || err.Error() != "Unresolved variables in environment dev: missing" {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestEnvironmentNames(t *testing.T) {
+	useTempVdatDir(t)
+	err := saveEnvironment(VdatEnvironment{Name: "dev", Variables: "host=example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	environment, err := loadEnvironment("dev")
+	if err != nil || environment.Variables != "host=example.com" {
+		t.Errorf("dev = %+v, %v", environment, err)
+	}
+
+	for _, name := range []string{"../../etc/passwd", "a/b", ".hidden", "..", "/abs"} {
+		_, err := loadEnvironment(name)
+		if err == nil || err.Error() != "Invalid environment name: "+name {
+			t.Errorf("loadEnvironment(%q) err = %v", name, err)
+		}
+		err = saveEnvironment(VdatEnvironment{Name: name})
+		if err == nil || err.Error() != "Invalid environment name: "+name {
+			t.Errorf("saveEnvironment(%q) err = %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,336 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// {{ _.name }} is how Insomnia refers to the variable vdat writes {{name}}
+var insomniaVariablePattern = regexp.MustCompile(`\{\{\s*_\.([A-Za-z0-9_.\-]+)\s*\}\}`)
+
+type insomniaExport struct {
+	Resources []insomniaResource `json:"resources"`
+}
+
+// insomniaResource holds the fields of every resource type that is read,
+// workspaces, request groups, requests and environments.
+type insomniaResource struct {
+	Id             string                 `json:"_id"`
+	Type           string                 `json:"_type"`
+	ParentId       string                 `json:"parentId"`
+	Name           string                 `json:"name"`
+	Method         string                 `json:"method"`
+	Url            string                 `json:"url"`
+	Body           insomniaBody           `json:"body"`
+	Parameters     []insomniaPair         `json:"parameters"`
+	PathParameters []insomniaPair         `json:"pathParameters"`
+	Headers        []insomniaPair         `json:"headers"`
+	Authentication map[string]looseString `json:"authentication"`
+	Data           map[string]any         `json:"data"`
+	Environment    map[string]any         `json:"environment"`
+	Cookies        []json.RawMessage      `json:"cookies"`
+	PreRequest     string                 `json:"preRequestScript"`
+	AfterResponse  string                 `json:"afterResponseScript"`
+}
+
+type insomniaPair struct {
+	Name        string `json:"name"`
+	Value       string `json:"value"`
+	Disabled    bool   `json:"disabled"`
+	Description string `json:"description"`
+	Type        string `json:"type"`
+	FileName    string `json:"fileName"`
+}
+
+type insomniaBody struct {
+	MimeType string         `json:"mimeType"`
+	Text     string         `json:"text"`
+	Params   []insomniaPair `json:"params"`
+	FileName string         `json:"fileName"`
+}
+
+var insomniaOAuth2Grants = map[string]string{
+	"client_credentials": OAUTH2_GRANT_CLIENT_CREDENTIALS,
+	"password":           OAUTH2_GRANT_PASSWORD,
+	"refresh_token":      OAUTH2_GRANT_REFRESH_TOKEN,
+	"authorization_code": OAUTH2_GRANT_AUTHORIZATION_CODE,
+}
+
+func insomniaRows(pairs []insomniaPair) []KeyValueRow {
+	rows := []KeyValueRow{}
+	for _, pair := range pairs {
+		rows = append(rows, KeyValueRow{
+			KeyValue:    KeyValue{Key: pair.Name, Value: pair.Value, HasValue: true},
+			Description: strings.Join(strings.Fields(pair.Description), " "),
+			Enabled:     !pair.Disabled,
+		})
+	}
+	return rows
+}
+
+// flattenInsomniaData turns nested environment data into variables named
+// the way Insomnia refers to them, {{ _.server.host }} becomes server.host.
+func flattenInsomniaData(prefix string, value any, variables map[string]string) {
+	switch value := value.(type) {
+	case map[string]any:
+		for key, child := range value {
+			name := key
+			if prefix != "" {
+				name = prefix + "." + key
+			}
+			flattenInsomniaData(name, child, variables)
+		}
+	case string:
+		variables[prefix] = value
+	case nil:
+		variables[prefix] = ""
+	default:
+		content, _ := json.Marshal(value)
+		variables[prefix] = string(content)
+	}
+}
+
+func insomniaEnvironmentRows(data ...map[string]any) []KeyValueRow {
+	variables := make(map[string]string)
+	for _, values := range data {
+		flattenInsomniaData("", values, variables)
+	}
+	names := []string{}
+	for name := range variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	rows := []KeyValueRow{}
+	for _, name := range names {
+		rows = append(rows, KeyValueRow{KeyValue: KeyValue{Key: name, Value: variables[name], HasValue: true}, Enabled: true})
+	}
+	return rows
+}
+
+func insomniaAuthToVdat(auth map[string]looseString, collection *collectionImport, where string) VdatAuth {
+	if auth["disabled"] == "true" {
+		return VdatAuth{Type: AUTH_TYPE_NONE}
+	}
+	switch auth["type"] {
+	case "", "none":
+		return VdatAuth{Type: AUTH_TYPE_NONE}
+	case "basic":
+		return VdatAuth{Type: AUTH_TYPE_BASIC, Username: string(auth["username"]), Password: string(auth["password"])}
+	case "digest":
+		return VdatAuth{Type: AUTH_TYPE_DIGEST, Username: string(auth["username"]), Password: string(auth["password"])}
+	case "bearer":
+		if prefix := string(auth["prefix"]); prefix != "" && !strings.EqualFold(prefix, "Bearer") {
+			collection.warn(where, "bearer prefix ", prefix, " is not supported, using Bearer")
+		}
+		return VdatAuth{Type: AUTH_TYPE_BEARER, Token: string(auth["token"])}
+	case "apikey":
+		in := API_KEY_IN_HEADER
+		switch auth["addTo"] {
+		case "queryParams":
+			in = API_KEY_IN_QUERY
+		case "cookie":
+			collection.warn(where, "API key in a cookie is not supported, sent as a header")
+		}
+		return VdatAuth{Type: AUTH_TYPE_API_KEY, Key: string(auth["key"]), Value: string(auth["value"]), In: in}
+	case "iam":
+		return VdatAuth{Type: AUTH_TYPE_AWS_SIGV4, AccessKey: string(auth["accessKeyId"]), SecretKey: string(auth["secretAccessKey"]), Region: string(auth["region"]), Service: string(auth["service"]), SessionToken: string(auth["sessionToken"])}
+	case "oauth2":
+		grant, found := insomniaOAuth2Grants[string(auth["grantType"])]
+		if !found {
+			grant = OAUTH2_GRANT_AUTHORIZATION_CODE
+			collection.warn(where, "OAuth 2.0 grant ", auth["grantType"], " is not supported, using ", grant)
+		}
+		return VdatAuth{
+			Type:         AUTH_TYPE_OAUTH2,
+			Grant:        grant,
+			TokenUrl:     string(auth["accessTokenUrl"]),
+			AuthUrl:      string(auth["authorizationUrl"]),
+			ClientId:     string(auth["clientId"]),
+			ClientSecret: string(auth["clientSecret"]),
+			Scope:        string(auth["scope"]),
+			Username:     string(auth["username"]),
+			Password:     string(auth["password"]),
+		}
+	}
+	collection.warn(where, "auth type ", auth["type"], " is not supported")
+	return VdatAuth{Type: AUTH_TYPE_NONE}
+}
+
+func insomniaRequestToVdat(resource insomniaResource, inheritedAuth map[string]looseString, collection *collectionImport, where string) VdatRequest {
+	vdatRequest := VdatRequest{
+		Title:      resource.Name,
+		RestMethod: collection.method(where, resource.Method),
+		SslEnabled: true,
+		BodyType:   BODY_TYPE_NONE,
+	}
+	if strings.TrimSpace(resource.PreRequest+resource.AfterResponse) != "" {
+		collection.warn(where, "scripts are not converted")
+	}
+
+	// Insomnia sends the query typed in the url followed by the parameters
+	base, query := splitUrlQuery(resource.Url)
+	vdatRequest.Url = base
+	params := []KeyValueRow{}
+	for _, pair := range readableQueryPairs(query) {
+		params = append(params, KeyValueRow{KeyValue: pair, Enabled: true})
+	}
+	params = append(params, literalRows(insomniaRows(resource.Parameters))...)
+	vdatRequest.Params = formatKeyValueRows(params, "=")
+	vdatRequest.PathParams = formatKeyValueRows(insomniaRows(resource.PathParameters), "=")
+
+	auth := inheritedAuth
+	if resource.Authentication["type"] != "" {
+		auth = resource.Authentication
+	}
+	vdatRequest.Auth = insomniaAuthToVdat(auth, collection, where)
+
+	body := resource.Body
+	mimeType, _, _ := strings.Cut(body.MimeType, ";")
+	switch {
+	case mimeType == "" && body.FileName == "":
+	case mimeType == CONTENT_TYPE_FORM:
+		vdatRequest.BodyType = BODY_TYPE_FORM
+		vdatRequest.BodyContent = formatKeyValueRows(literalRows(insomniaRows(body.Params)), "=")
+	case mimeType == "multipart/form-data":
+		vdatRequest.BodyType = BODY_TYPE_MULTIPART
+		lines := []string{}
+		for _, param := range body.Params {
+			part := VdatMultipartPart{Name: param.Name, Value: param.Value}
+			if param.Type == "file" {
+				part = VdatMultipartPart{Name: param.Name, Value: param.FileName, IsFile: true}
+				if param.FileName == "" {
+					collection.warn(where, "form file ", param.Name, " has no file selected")
+					continue
+				}
+			} else if strings.HasPrefix(param.Value, "@") || strings.HasPrefix(param.Value, "<") {
+				collection.warn(where, "form value ", param.Name, " starts with ", param.Value[:1], " and is read as a file")
+			}
+			line := part.String()
+			if param.Disabled {
+				line = "#" + line
+			}
+			lines = append(lines, line)
+		}
+		vdatRequest.BodyContent = strings.Join(lines, "\n")
+	case mimeType == "application/graphql":
+		// the text is already the JSON object sent
+		vdatRequest.BodyType = BODY_TYPE_RAW
+		vdatRequest.RawLanguage = RAW_LANGUAGE_JSON
+		vdatRequest.BodyContent = body.Text
+	case body.FileName != "":
+		vdatRequest.BodyType = BODY_TYPE_FILE
+		vdatRequest.BodyFile = body.FileName
+	default:
+		vdatRequest.BodyType = BODY_TYPE_RAW
+		vdatRequest.RawLanguage = rawLanguageForContentType(mimeType)
+		vdatRequest.BodyContent = body.Text
+	}
+
+	headers := insomniaRows(resource.Headers)
+	if body.MimeType != "" && mimeType != "application/graphql" && !hasHeader(formatKeyValueRows(headers, "\t"), "Content-Type") {
+		headers = append(headers, KeyValueRow{KeyValue: KeyValue{Key: "Content-Type", Value: body.MimeType, HasValue: true}, Enabled: true})
+	}
+	vdatRequest.Headers = formatKeyValueRows(withoutImpliedContentType(headers, vdatRequest), "\t")
+
+	if strings.Contains(fmt.Sprint(vdatRequest), "{%") {
+		collection.warn(where, "template tags are not converted")
+	}
+	return vdatRequest
+}
+
+func importInsomniaResources(children map[string][]insomniaResource, parentId string, folder string, inheritedAuth map[string]looseString, collection *collectionImport, where string) error {
+	for _, resource := range children[parentId] {
+		resourceWhere := where + "/" + resource.Name
+		switch resource.Type {
+		case "request_group":
+			subfolder, err := collection.addFolder(folder, resource.Name)
+			if err != nil {
+				return err
+			}
+			if len(resource.Environment) != 0 {
+				collection.warn(resourceWhere, "folder environment is not converted")
+			}
+			if strings.TrimSpace(resource.PreRequest+resource.AfterResponse) != "" {
+				collection.warn(resourceWhere, "folder scripts are not converted")
+			}
+			auth := inheritedAuth
+			if resource.Authentication["type"] != "" {
+				auth = resource.Authentication
+			}
+			err = importInsomniaResources(children, resource.Id, subfolder, auth, collection, resourceWhere)
+			if err != nil {
+				return err
+			}
+		case "request":
+			err := collection.addRequest(folder, insomniaRequestToVdat(resource, inheritedAuth, collection, resourceWhere))
+			if err != nil {
+				return err
+			}
+		case "environment", "api_spec":
+		case "cookie_jar":
+			if len(resource.Cookies) != 0 {
+				collection.warn(resourceWhere, "cookies are not converted")
+			}
+		default:
+			collection.warn(resourceWhere, resource.Type, " is not supported")
+		}
+	}
+	return nil
+}
+
+// importInsomniaExport reads an Insomnia v4 export, a folder for each
+// workspace. The base environment becomes an environment named after the
+// workspace and each sub environment one named "workspace - name", holding
+// the base variables as well.
+func importInsomniaExport(content []byte, parent string, collection *collectionImport) error {
+	content = insomniaVariablePattern.ReplaceAll(content, []byte("{{$1}}"))
+	var export insomniaExport
+	err := json.Unmarshal(content, &export)
+	if err != nil {
+		return errors.New(fmt.Sprint("Error reading Insomnia export: ", err))
+	}
+
+	children := make(map[string][]insomniaResource)
+	environments := make(map[string][]insomniaResource)
+	workspaces := []insomniaResource{}
+	for _, resource := range export.Resources {
+		switch resource.Type {
+		case "workspace":
+			workspaces = append(workspaces, resource)
+		case "environment":
+			environments[resource.ParentId] = append(environments[resource.ParentId], resource)
+		}
+		children[resource.ParentId] = append(children[resource.ParentId], resource)
+	}
+	if len(workspaces) == 0 {
+		return errors.New("No workspace in Insomnia export")
+	}
+
+	for _, workspace := range workspaces {
+		folder, err := collection.addCollection(parent, workspace.Name)
+		if err != nil {
+			return err
+		}
+		err = importInsomniaResources(children, workspace.Id, folder, nil, collection, workspace.Name)
+		if err != nil {
+			return err
+		}
+		for _, base := range environments[workspace.Id] {
+			err = collection.addEnvironment(workspace.Name, insomniaEnvironmentRows(base.Data))
+			if err != nil {
+				return err
+			}
+			for _, environment := range environments[base.Id] {
+				err = collection.addEnvironment(workspace.Name+" - "+environment.Name, insomniaEnvironmentRows(base.Data, environment.Data))
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInsomniaEnvironmentRows(t *testing.T) {
+	rows := insomniaEnvironmentRows(
+		map[string]any{"server": map[string]any{"host": "base.example.com", "port": 443.0}, "token": nil},
+		map[string]any{"server": map[string]any{"host": "dev.example.com"}, "tags": []any{"a", "b"}},
+	)
+	want := "server.host=dev.example.com\nserver.port=443\ntags=[\"a\",\"b\"]\ntoken="
+	if got := formatKeyValueRows(rows, "="); got != want {
+		t.Errorf("rows = %q, want %q", got, want)
+	}
+}
+
+func TestImportInsomniaExport(t *testing.T) {
+	useTempVdatDir(t)
+	vdatDir, err := getVdatDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := `{
+	"_type": "export",
+	"__export_format": 4,
+	"resources": [
+		{"_id": "wrk_1", "_type": "workspace", "parentId": null, "name": "Shop"},
+		{"_id": "env_base", "_type": "environment", "parentId": "wrk_1", "name": "Base", "data": {"server": {"host": "api.example.com"}, "token": "base-token"}},
+		{"_id": "env_dev", "_type": "environment", "parentId": "env_base", "name": "Dev", "data": {"server": {"host": "dev.example.com"}}},
+		{"_id": "fld_1", "_type": "request_group", "parentId": "wrk_1", "name": "Admin",
+			"authentication": {"type": "basic", "username": "admin", "password": "{{ _.password }}"}},
+		{"_id": "req_1", "_type": "request", "parentId": "fld_1", "name": "Users", "method": "GET",
+			"url": "https://{{ _.server.host }}/users?active=true#list",
+			"parameters": [{"name": "page", "value": "2"}, {"name": "debug", "value": "1", "disabled": true}],
+			"authentication": {}},
+		{"_id": "req_2", "_type": "request", "parentId": "fld_1", "name": "Public", "method": "GET",
+			"url": "https://example.com/public", "authentication": {"type": "none"}},
+		{"_id": "req_3", "_type": "request", "parentId": "wrk_1", "name": "Create", "method": "POST",
+			"url": "https://example.com/items",
+			"body": {"mimeType": "application/json", "text": "{\"a\": 1}"},
+			"headers": [{"name": "Content-Type", "value": "application/json"}],
+			"authentication": {"type": "bearer", "token": "{{ _.token }}", "disabled": true}}
+	]
+}`
+	path := filepath.Join(t.TempDir(), "insomnia.json")
+	err = os.WriteFile(path, []byte(content), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	collection, err := importCollection(path, vdatDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(collection.warnings) != 0 || collection.requests != 3 {
+		t.Errorf("imported %d requests, warnings = %q", collection.requests, collection.warnings)
+	}
+
+	users, err := loadVdatRequest(filepath.Join(vdatDir, "Shop", "Admin", "GET - Users"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if users.Url != "https://{{server.host}}/users" || users.Params != "active=true\npage=2\n#debug=1" {
+		t.Errorf("url %q, params %q", users.Url, users.Params)
+	}
+	// a request without auth of its own inherits the folder's
+	if users.Auth != (VdatAuth{Type: AUTH_TYPE_BASIC, Username: "admin", Password: "{{password}}"}) {
+		t.Errorf("inherited auth = %+v", users.Auth)
+	}
+	public, err := loadVdatRequest(filepath.Join(vdatDir, "Shop", "Admin", "GET - Public"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if public.Auth.Type != AUTH_TYPE_NONE {
+		t.Errorf("none auth = %+v", public.Auth)
+	}
+	create, err := loadVdatRequest(filepath.Join(vdatDir, "Shop", "POST - Create"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if create.Auth.Type != AUTH_TYPE_NONE || create.BodyType != BODY_TYPE_RAW || create.RawLanguage != RAW_LANGUAGE_JSON || create.Headers != "" {
+		t.Errorf("create = %+v", create)
+	}
+
+	// the sub environment holds the base variables with its own on top
+	for name, want := range map[string]string{
+		"Shop":       "server.host=api.example.com\ntoken=base-token",
+		"Shop - Dev": "server.host=dev.example.com\ntoken=base-token",
+	} {
+		environment, err := loadEnvironment(name)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if environment.Variables != want {
+			t.Errorf("%s: Variables = %q, want %q", name, environment.Variables, want)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+type KeyValueRow struct {
+	KeyValue
+	Description string
+	Enabled     bool
+}
+
+// parseKeyValueRows reads the text format used by the params, headers and
+// form entries. A line starting with "#" that holds a key and value is a
+// disabled row, any other "#" line describes the row that follows it.
+func parseKeyValueRows(text string, separator string) []KeyValueRow {
+	rows := []KeyValueRow{}
+	descriptions := []string{}
+	for _, line := range strings.Split(text, "\n") {
+		if line == "" {
+			continue
+		}
+		enabled := true
+		if line[0] == '#' {
+			line = line[1:]
+			if strings.HasPrefix(line, "#") || !strings.Contains(line, separator) {
+				descriptions = append(descriptions, strings.TrimSpace(strings.TrimLeft(line, "#")))
+				continue
+			}
+			enabled = false
+		}
+		key, value, found := strings.Cut(line, separator)
+		rows = append(rows, KeyValueRow{
+			KeyValue:    KeyValue{Key: key, Value: value, HasValue: found},
+			Description: strings.Join(descriptions, " "),
+			Enabled:     enabled,
+		})
+		descriptions = []string{}
+	}
+	if len(descriptions) != 0 {
+		rows = append(rows, KeyValueRow{Description: strings.Join(descriptions, " ")})
+	}
+	return rows
+}
+
+func formatKeyValueRows(rows []KeyValueRow, separator string) string {
+	lines := []string{}
+	for _, row := range rows {
+		if row.Description != "" {
+			lines = append(lines, "## "+row.Description)
+		}
+		if row.Key == "" && row.Value == "" {
+			continue
+		}
+		line := row.Key
+		if row.HasValue || row.Value != "" {
+			line += separator + row.Value
+		}
+		if !row.Enabled {
+			line = "#" + line
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+type KeyValueEditor struct {
+	content      *fyne.Container
+	entry        *widget.Entry
+	separator    string
+	rows         []KeyValueRow
+	table        *fyne.Container
+	tableScroll  *container.Scroll
+	toggleButton *widget.Button
+	addButton    *widget.Button
+	tableMode    bool
+	tableAllowed bool
+	updating     bool
+}
+
+func newKeyValueEditor(entry *widget.Entry, separator string) *KeyValueEditor {
+	editor := &KeyValueEditor{
+		entry:        entry,
+		separator:    separator,
+		table:        container.NewVBox(),
+		tableMode:    true,
+		tableAllowed: true,
+	}
+	editor.tableScroll = container.NewVScroll(editor.table)
+	editor.toggleButton = widget.NewButton(BULK_EDIT_BUTTON_TEXT, func() {
+		editor.tableMode = !editor.tableMode
+		editor.show()
+	})
+	editor.addButton = widget.NewButton(ADD_ROW_BUTTON_TEXT, func() {
+		editor.rows = append(editor.rows, KeyValueRow{Enabled: true})
+		editor.rebuild()
+	})
+	controls := container.NewBorder(nil, nil, nil, container.NewHBox(editor.addButton, editor.toggleButton), nil)
+	editor.content = container.NewBorder(controls, nil, nil, nil, container.NewStack(editor.tableScroll, entry))
+	editor.show()
+	return editor
+}
+
+func (editor *KeyValueEditor) setTableAllowed(allowed bool) {
+	editor.tableAllowed = allowed
+	editor.show()
+}
+
+// refresh rebuilds the table after the entry text changed outside the table.
+func (editor *KeyValueEditor) refresh() {
+	if editor.updating || !editor.tableMode || !editor.tableAllowed {
+		return
+	}
+	editor.rows = parseKeyValueRows(editor.entry.Text, editor.separator)
+	editor.rebuild()
+}
+
+func (editor *KeyValueEditor) show() {
+	if editor.tableMode && editor.tableAllowed {
+		editor.rows = parseKeyValueRows(editor.entry.Text, editor.separator)
+		editor.rebuild()
+		editor.entry.Hide()
+		editor.tableScroll.Show()
+		editor.addButton.Show()
+		editor.toggleButton.SetText(BULK_EDIT_BUTTON_TEXT)
+	} else {
+		editor.tableScroll.Hide()
+		editor.addButton.Hide()
+		editor.entry.Show()
+		editor.toggleButton.SetText(TABLE_BUTTON_TEXT)
+	}
+	if editor.tableAllowed {
+		editor.toggleButton.Show()
+	} else {
+		editor.toggleButton.Hide()
+	}
+}
+
+func (editor *KeyValueEditor) write() {
+	editor.updating = true
+	editor.entry.SetText(formatKeyValueRows(editor.rows, editor.separator))
+	editor.updating = false
+}
+
+func (editor *KeyValueEditor) rebuild() {
+	editor.table.RemoveAll()
+	editor.table.Add(container.NewGridWithColumns(3,
+		widget.NewLabel(KEY_COLUMN_TEXT),
+		widget.NewLabel(VALUE_COLUMN_TEXT),
+		widget.NewLabel(DESCRIPTION_COLUMN_TEXT)))
+	for index := range editor.rows {
+		row := &editor.rows[index]
+
+		enabled := widget.NewCheck("", nil)
+		enabled.SetChecked(row.Enabled)
+		enabled.OnChanged = func(checked bool) {
+			row.Enabled = checked
+			editor.write()
+		}
+		key := widget.NewEntry()
+		key.TextStyle.Monospace = true
+		key.SetText(row.Key)
+		key.OnChanged = func(text string) {
+			row.Key = text
+			editor.write()
+		}
+		value := widget.NewEntry()
+		value.TextStyle.Monospace = true
+		value.SetText(row.Value)
+		value.OnChanged = func(text string) {
+			row.Value = text
+			editor.write()
+		}
+		description := widget.NewEntry()
+		description.SetText(row.Description)
+		description.OnChanged = func(text string) {
+			row.Description = strings.ReplaceAll(text, "\n", " ")
+			editor.write()
+		}
+		removeButton := widget.NewButton(REMOVE_ROW_BUTTON_TEXT, func() {
+			editor.rows = append(editor.rows[:index], editor.rows[index+1:]...)
+			editor.write()
+			editor.rebuild()
+		})
+
+		editor.table.Add(container.NewBorder(nil, nil, enabled, removeButton, container.NewGridWithColumns(3, key, value, description)))
+	}
+	editor.table.Refresh()
+}
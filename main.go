@@ -1,13 +1,10 @@
 package main
 
 import (
-	"crypto/tls"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
-	"net/http"
-	"net/url"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -20,162 +17,11 @@ import (
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/layout"
 	"fyne.io/fyne/v2/widget"
 )
 
-func parseCurlCommand(curlCommand string) (VdatRequest, error) {
-	// Trim any extra spaces
-	curlCommand = strings.TrimSpace(curlCommand)
-
-	// Split the command into tokens
-	// Initialize a variable to store tokens
-	var tokens []string
-	var currentToken strings.Builder
-	inQuotesDouble := false
-	inQuotesSingle := false
-	escaped := false
-
-	// Tokenize the command while preserving quoted strings
-	for _, char := range curlCommand {
-		if escaped {
-			currentToken.WriteRune(char)
-			escaped = false
-			continue
-		}
-		switch char {
-		case '\\':
-			escaped = true
-		case '"':
-			if !inQuotesSingle {
-				inQuotesDouble = !inQuotesDouble // Toggle the inQuotesDouble flag
-			} else {
-				currentToken.WriteRune(char)
-			}
-		case '\'':
-			if !inQuotesDouble {
-				inQuotesSingle = !inQuotesSingle // Toggle the inQuotesSingle flag
-			} else {
-				currentToken.WriteRune(char)
-			}
-		case ' ':
-			if inQuotesDouble || inQuotesSingle {
-				currentToken.WriteRune(char) // Keep spaces inside any quotes
-			} else {
-				if currentToken.Len() > 0 {
-					tokens = append(tokens, currentToken.String())
-					currentToken.Reset()
-				}
-			}
-		default:
-			currentToken.WriteRune(char) // Append the current character to the token
-		}
-	}
-
-	// Append the last token if any
-	if currentToken.Len() > 0 {
-		tokens = append(tokens, currentToken.String())
-	}
-
-	if len(tokens) == 0 || tokens[0] != "curl" {
-		return VdatRequest{}, errors.New("invalid curl command format")
-	}
-
-	if len(tokens) == 0 || tokens[0] != "curl" {
-		return VdatRequest{}, errors.New("invalid curl command format")
-	}
-
-	var req VdatRequest
-	var bodyFlag bool
-	var contentType string
-
-	req.Title = TITLE_DEFAULT
-
-	// Process each token
-	for i := 1; i < len(tokens); i++ {
-		token := tokens[i]
-
-		switch strings.TrimSpace(token) {
-		case "-X", "--request":
-			if i+1 < len(tokens) {
-				if containsString(REST_METHODS, tokens[i+1]) {
-					req.RestMethod = tokens[i+1]
-					i++ // Skip the method token
-					req.Url = strings.Trim(tokens[i+1], "\"")
-					i++ // Skip the URL token
-				} else {
-					req.RestMethod = http.MethodGet
-					req.Url = strings.Trim(tokens[i+1], "\"")
-					i++
-				}
-			}
-		case "-H", "--header":
-			if i+1 < len(tokens) {
-				header := tokens[i+1]
-				i++ // Skip the header value token
-				key, value, found := strings.Cut(header, ":")
-				if found {
-					key = strings.TrimSpace(key)
-					value = strings.TrimSpace(value)
-					if strings.ToLower(key) == "content-type" {
-						contentType = value
-					}
-					req.Headers += key + "\t" + value + "\n"
-				} else {
-					return VdatRequest{}, errors.New(fmt.Sprint("invalid header: ", header))
-				}
-			}
-		case "-d", "--data", "--data-raw":
-			if i+1 < len(tokens) {
-				if req.BodyContent == "" {
-					req.BodyContent = tokens[i+1]
-				} else {
-					req.BodyContent = req.BodyContent + "\n" + tokens[i+1]
-				}
-				bodyFlag = true
-				i++ // Skip the body content token
-			}
-		default:
-			// Check if the token is a URL
-			if strings.HasPrefix(token, "http://") || strings.HasPrefix(token, "https://") || strings.HasPrefix(token, "\"http://") || strings.HasPrefix(token, "\"https://") {
-				req.Url = token
-			}
-		}
-	}
-
-	// Set default RestMethod if not specified
-	if req.RestMethod == "" {
-		req.RestMethod = "GET" // Default method
-	}
-
-	// Assuming JSON for the body type
-	if !bodyFlag {
-		req.BodyType = BODY_TYPE_NONE
-	} else if strings.ToLower(contentType) == "application/x-www-form-urlencoded" {
-		req.BodyType = BODY_TYPE_FORM
-		req.BodyContent = strings.Join(strings.Split(req.BodyContent, "&"), "\n")
-	} else {
-		req.BodyType = BODY_TYPE_RAW
-	}
-
-	parsedURL, err := url.Parse(req.Url)
-	if err != nil {
-		return VdatRequest{}, errors.New(fmt.Sprint("Error parsing URL:", req.Url))
-	}
-
-	// Get query parameters
-	queryParams := parsedURL.Query()
-
-	// Print query parameters
-	for key, values := range queryParams {
-		req.Params += strings.TrimSpace(key) + "=" + strings.TrimSpace(strings.Join(values, ",")) + "\n"
-	}
-	parsedURL.RawQuery = ""
-	req.Url = parsedURL.String()
-
-	return req, nil
-}
-
 func checkDirExists(dir string) (bool, error) {
 	info, err := os.Stat(dir)
 	if os.IsNotExist(err) {
@@ -228,6 +74,9 @@ func fileTreeLoadChildren(id widget.TreeNodeID) (children []widget.TreeNodeID) {
 		return
 	}
 	for _, file := range files {
+		if strings.HasPrefix(file.Name(), ".") {
+			continue
+		}
 		childPath := filepath.Join(id, file.Name())
 		children = append(children, childPath)
 	}
@@ -257,6 +106,31 @@ func errorPopUp(canvas fyne.Canvas, err error) {
 	popUp.Show()
 }
 
+func messagePopUp(canvas fyne.Canvas, message string) {
+	modalContent := container.NewVBox(widget.NewLabel(message))
+	popUp := widget.NewModalPopUp(modalContent, canvas)
+	okButton := widget.NewButton(OK_BUTTON_TEXT, func() { popUp.Hide() })
+	modalContent.Add(okButton)
+	popUp.Show()
+}
+
+func copyTextPopUp(window fyne.Window, message string, text string) {
+	entry := widget.NewMultiLineEntry()
+	entry.SetText(text)
+	entry.TextStyle.Monospace = true
+	entry.SetMinRowsVisible(10)
+	modalContent := container.NewVBox(widget.NewLabel(message), entry)
+	popUp := widget.NewModalPopUp(modalContent, window.Canvas())
+	copyButton := widget.NewButton(COPY_BUTTON_TEXT, func() {
+		window.Clipboard().SetContent(entry.Text)
+		popUp.Hide()
+	})
+	closeButton := widget.NewButton(CLOSE_BUTTON_TEXT, func() { popUp.Hide() })
+	modalContent.Add(container.NewHBox(copyButton, closeButton))
+	popUp.Resize(fyne.NewSize(window.Canvas().Size().Width*2/3, popUp.MinSize().Height))
+	popUp.Show()
+}
+
 func getStringPopUp(canvas fyne.Canvas, message string) <-chan string {
 	entry := widget.NewEntry()
 	modalContent := container.NewVBox(widget.NewLabel(message), entry)
@@ -275,8 +149,9 @@ func getStringPopUp(canvas fyne.Canvas, message string) <-chan string {
 	return resultCh // Return the channel
 }
 
-func getMultilineStringPopUp(canvas fyne.Canvas, message string) <-chan string {
+func getMultilineStringPopUp(canvas fyne.Canvas, message string, text string) <-chan string {
 	entry := widget.NewMultiLineEntry()
+	entry.SetText(text)
 	modalContent := container.NewVBox(widget.NewLabel(message), entry)
 	popUp := widget.NewModalPopUp(modalContent, canvas)
 
@@ -319,13 +194,25 @@ func confirmationPopup(canvas fyne.Canvas, message string) <-chan bool {
 	return resultCh // Return the channel
 }
 
-func containsRune(slice []rune, element rune) bool {
-	for _, item := range slice {
-		if item == element {
-			return true
-		}
-	}
-	return false
+func newFileBrowseControls(window fyne.Window, entry *widget.Entry, requestPath func() string) *fyne.Container {
+	browseButton := widget.NewButton(BROWSE_BUTTON_TEXT, func() {
+		dialog.ShowFileOpen(func(reader fyne.URIReadCloser, err error) {
+			if err != nil {
+				errorPopUp(window.Canvas(), err)
+				return
+			}
+			if reader == nil {
+				return
+			}
+			defer reader.Close()
+			path := ""
+			if requestPath != nil {
+				path = requestPath()
+			}
+			entry.SetText(relativeBodyFilePath(reader.URI().Path(), path))
+		}, window)
+	})
+	return container.NewBorder(nil, nil, nil, browseButton, entry)
 }
 
 func containsString(slice []string, element string) bool {
@@ -337,59 +224,116 @@ func containsString(slice []string, element string) bool {
 	return false
 }
 
-func validRunes(value string) bool {
-	for _, r := range value {
-		if !containsRune(VALID_RUNES, r) {
-			return false
-		}
-	}
-	return true
-}
-
 type SaveCallback func(string, string) error
 type LoadCallback func(string) (string, error)
+type ShowCallback func(VdatRequest) string
 type PathCallback func() string
+type RequestCallback func() VdatRequest
+type SettingsCallback func() VdatSettings
+type WindowCallbacks struct {
+	environmentCallback EnvironmentCallback
+	settingsCallback    SettingsCallback
+}
 type TabCallbacks struct {
-	saveCallback SaveCallback
-	loadCallback LoadCallback
-	pathCallback PathCallback
+	saveCallback    SaveCallback
+	loadCallback    LoadCallback
+	showCallback    ShowCallback
+	pathCallback    PathCallback
+	requestCallback RequestCallback
 }
 type VdatRequest struct {
-	Headers     string `json:"Headers"`
-	Params      string `json:"Params"`
-	BodyContent string `json:"BodyContent"`
-	BodyType    string `json:"BodyType"`
-	Url         string `json:"Url"`
-	Title       string `json:"Title"`
-	RestMethod  string `json:"RestMethod"`
-	SslEnabled  bool   `json:"SslEnabled"`
+	Headers     string       `json:"Headers"`
+	Params      string       `json:"Params"`
+	PathParams  string       `json:"PathParams"`
+	BodyContent string       `json:"BodyContent"`
+	BodyType    string       `json:"BodyType"`
+	BodyFile    string       `json:"BodyFile"`
+	RawLanguage string       `json:"RawLanguage"`
+	Url         string       `json:"Url"`
+	Title       string       `json:"Title"`
+	RestMethod  string       `json:"RestMethod"`
+	SslEnabled  bool         `json:"SslEnabled"`
+	Timeouts    VdatTimeouts `json:"Timeouts"`
+	Auth        VdatAuth     `json:"Auth"`
+	Tls         VdatTls      `json:"Tls"`
+	Proxy       VdatProxy    `json:"Proxy"`
+	// where the request is saved, relative body files start from its folder
+	Path string `json:"-"`
 }
 
-func makeNewTabContent(canvas fyne.Canvas) (fyne.CanvasObject, TabCallbacks) {
+func makeNewTabContent(window fyne.Window, windowCallbacks WindowCallbacks) (fyne.CanvasObject, TabCallbacks) {
 	var tabPath string
+	canvas := window.Canvas()
 	headers := widget.NewMultiLineEntry()
 	headers.TextStyle.Monospace = true
 	headers.SetPlaceHolder(HEADERS_PLACEHOLDER)
+	impliedHeadersLabel := widget.NewLabel("")
+	impliedHeadersLabel.TextStyle.Monospace = true
+	var refreshImpliedHeaders func()
+	var refreshUrlPreview func()
 	params := widget.NewMultiLineEntry()
 	params.TextStyle.Monospace = true
 	params.SetPlaceHolder(PARAMS_PLACEHOLDER)
+	pathParams := widget.NewMultiLineEntry()
+	pathParams.TextStyle.Monospace = true
+	pathParams.SetPlaceHolder(PATH_PARAMS_PLACEHOLDER)
 	bodyContent := widget.NewMultiLineEntry()
 	bodyContent.TextStyle.Monospace = true
-	bodyType := widget.NewSelect([]string{BODY_TYPE_FORM, BODY_TYPE_RAW, BODY_TYPE_NONE}, func(value string) {
+	headersEditor := newKeyValueEditor(headers, "\t")
+	paramsEditor := newKeyValueEditor(params, "=")
+	pathParamsEditor := newKeyValueEditor(pathParams, "=")
+	bodyEditor := newKeyValueEditor(bodyContent, "=")
+	bodyContent.OnChanged = func(string) { bodyEditor.refresh() }
+	bodyFile := widget.NewEntry()
+	bodyFile.SetPlaceHolder(BODY_FILE_PLACEHOLDER)
+	bodyFileControls := newFileBrowseControls(window, bodyFile, func() string { return tabPath })
+	bodyFileControls.Hide()
+	rawLanguage := widget.NewSelect(RAW_LANGUAGES, func(string) {
+		if refreshImpliedHeaders != nil {
+			refreshImpliedHeaders()
+		}
+	})
+	rawLanguage.PlaceHolder = RAW_LANGUAGE_PLACEHOLDER
+	rawLanguage.SetSelected(RAW_LANGUAGE_JSON)
+	beautifyButton := widget.NewButton(BEAUTIFY_BUTTON_TEXT, func() {
+		beautified, err := beautify(bodyContent.Text, rawLanguage.Selected)
+		if err != nil {
+			errorPopUp(canvas, err)
+			return
+		}
+		bodyContent.SetText(beautified)
+	})
+	rawControls := container.NewHBox(rawLanguage, beautifyButton)
+	rawControls.Hide()
+	bodyType := widget.NewSelect([]string{BODY_TYPE_FORM, BODY_TYPE_RAW, BODY_TYPE_MULTIPART, BODY_TYPE_FILE, BODY_TYPE_NONE}, func(value string) {
+		bodyFileControls.Hide()
+		rawControls.Hide()
+		bodyEditor.setTableAllowed(value == BODY_TYPE_FORM)
+		if refreshImpliedHeaders != nil {
+			defer refreshImpliedHeaders()
+		}
 		if value == BODY_TYPE_NONE {
 			bodyContent.Disable()
 			bodyContent.SetPlaceHolder(BODY_CONTENT_PLACEHOLDER_TYPE_NONE)
+		} else if value == BODY_TYPE_FILE {
+			bodyContent.Disable()
+			bodyContent.SetPlaceHolder(BODY_CONTENT_PLACEHOLDER_TYPE_NONE)
+			bodyFileControls.Show()
 		} else if value == BODY_TYPE_FORM {
 			bodyContent.Enable()
 			bodyContent.SetPlaceHolder(BODY_CONTENT_PLACEHOLDER_TYPE_FORM)
 		} else if value == BODY_TYPE_RAW {
 			bodyContent.Enable()
 			bodyContent.SetPlaceHolder(BODY_CONTENT_PLACEHOLDER_TYPE_RAW)
+			rawControls.Show()
+		} else if value == BODY_TYPE_MULTIPART {
+			bodyContent.Enable()
+			bodyContent.SetPlaceHolder(BODY_CONTENT_PLACEHOLDER_TYPE_MULTIPART)
 		}
 
 	})
 	bodyType.SetSelectedIndex(0)
-	bodyPane := container.NewBorder(bodyType, nil, nil, nil, bodyContent)
+	bodyPane := container.NewBorder(container.NewVBox(container.NewBorder(nil, nil, nil, rawControls, bodyType), bodyFileControls), nil, nil, nil, bodyEditor.content)
 	responseStatus := widget.NewEntry()
 	responseStatus.TextStyle.Monospace = true
 	responseStatus.SetPlaceHolder(RESPONSE_STATUS_PLACEHOLDER)
@@ -397,6 +341,21 @@ func makeNewTabContent(canvas fyne.Canvas) (fyne.CanvasObject, TabCallbacks) {
 	responseBody.TextStyle.Monospace = true
 	responseBody.SetPlaceHolder(RESPONSE_BODY_PLACEHOLDER)
 	responseBody.Wrapping = fyne.TextWrapWord
+	responseHeaders := widget.NewMultiLineEntry()
+	responseHeaders.TextStyle.Monospace = true
+	responseHeaders.SetPlaceHolder(RESPONSE_HEADERS_PLACEHOLDER)
+	responseCookies := widget.NewMultiLineEntry()
+	responseCookies.TextStyle.Monospace = true
+	responseCookies.SetPlaceHolder(RESPONSE_COOKIES_PLACEHOLDER)
+	responseInfo := widget.NewMultiLineEntry()
+	responseInfo.TextStyle.Monospace = true
+	responseInfo.SetPlaceHolder(RESPONSE_INFO_PLACEHOLDER)
+	responseTiming := widget.NewMultiLineEntry()
+	responseTiming.TextStyle.Monospace = true
+	responseTiming.SetPlaceHolder(RESPONSE_TIMING_PLACEHOLDER)
+	responseTls := widget.NewMultiLineEntry()
+	responseTls.TextStyle.Monospace = true
+	responseTls.SetPlaceHolder(RESPONSE_TLS_PLACEHOLDER)
 	responseTime := widget.NewEntry()
 	responseTime.TextStyle.Monospace = true
 	responseTime.SetPlaceHolder(RESPONSE_TIME_PLACEHOLDER)
@@ -408,210 +367,302 @@ func makeNewTabContent(canvas fyne.Canvas) (fyne.CanvasObject, TabCallbacks) {
 
 	sslCheckbox := widget.NewCheck(SSL_ENABLED_TEXT, nil)
 	sslCheckbox.SetChecked(true)
-	sendButton := widget.NewButton(SEND_BUTTON_TEXT, func() {
-		responseBody.SetText("")
-		responseStatus.SetText("")
-		responseTime.SetText("")
-
-		// prepare url with params
-		urlText := url.Text
-		paramsText := []string{}
-		for _, line := range strings.Split(params.Text, "\n") {
-			if line == "" || line[0] == '#' {
-				continue
-			}
-			key, value, found := strings.Cut(line, "=")
-			if found {
-				if key != "" && validRunes(key) && validRunes(value) {
-					paramsText = append(paramsText, key+"="+value)
-				} else {
-					errorPopUp(canvas, errors.New(fmt.Sprint("Error with param entry: ", key, "=", value)))
-					return
-				}
-			}
+	timeoutsForm, getTimeouts, setTimeouts := newTimeoutEntries(windowCallbacks.settingsCallback().Timeouts)
+	proxyForm, getProxy, setProxy := newProxyEntries(windowCallbacks.settingsCallback().Proxy)
+	tlsForm, getTls, setTls, setTlsPlaceholders := newTlsEntries(window, func() string { return tabPath })
+	tlsHostButton := widget.NewButton(HOST_TLS_BUTTON_TEXT, func() {
+		host := urlHostname(url.Text)
+		if host == "" {
+			errorPopUp(canvas, errors.New("Enter a url with a host first"))
+			return
 		}
-		if len(paramsText) != 0 {
-			urlText = urlText + "?" + strings.Join(paramsText, "&")
-		}
-
-		// prepare body
-		var body io.Reader
-		if bodyType.Selected == BODY_TYPE_NONE {
-			body = strings.NewReader(string(""))
-		} else if bodyType.Selected == BODY_TYPE_RAW {
-			bodyContent.Text = smartFormat([]byte(bodyContent.Text))
-			body = strings.NewReader(bodyContent.Text)
-		} else if bodyType.Selected == BODY_TYPE_FORM {
-			bodyText := []string{}
-			for _, line := range strings.Split(bodyContent.Text, "\n") {
-				if line == "" || line[0] == '#' {
-					continue
-				}
-				key, value, found := strings.Cut(line, "=")
-				if found {
-					if key != "" && validRunes(key) && validRunes(value) {
-						bodyText = append(bodyText, key+"="+value)
-					} else {
-						errorPopUp(canvas, errors.New(fmt.Sprint("Error with body entry: ", key, "=", value)))
-						return
-					}
-				}
-			}
-			finalBodyText := ""
-			if len(bodyText) != 0 {
-				finalBodyText = strings.Join(bodyText, "&")
-			}
-			body = strings.NewReader(finalBodyText)
-
+		tlsHostPopUp(canvas, window, host)
+	})
+	authForm, getAuth, setAuth := newAuthEntries(func() {
+		if refreshImpliedHeaders != nil {
+			refreshImpliedHeaders()
+		}
+		if refreshUrlPreview != nil {
+			refreshUrlPreview()
+		}
+	}, func(auth VdatAuth) {
+		environment, err := windowCallbacks.environmentCallback()
+		if err == nil {
+			var vdatRequest VdatRequest
+			vdatRequest, err = applyEnvironment(VdatRequest{Auth: auth}, environment)
+			auth = vdatRequest.Auth
+		}
+		if err == nil {
+			err = clearCachedToken(environment.Name, auth)
 		}
-
-		// create request
-		req, err := http.NewRequest(restMethod.Selected, urlText, body)
 		if err != nil {
 			errorPopUp(canvas, err)
-			return
 		}
-
-		// parse form if applicable
-		if bodyType.Selected == BODY_TYPE_FORM {
-			err = req.ParseForm()
-			if err != nil {
-				errorPopUp(canvas, err)
-				return
-			}
+	})
+	sendProgress := widget.NewProgressBarInfinite()
+	sendProgress.Hide()
+	var cancelSend context.CancelFunc
+	currentVdatRequest := func(title string) VdatRequest {
+		return VdatRequest{
+			Headers:     headers.Text,
+			Params:      params.Text,
+			PathParams:  pathParams.Text,
+			BodyContent: bodyContent.Text,
+			BodyType:    bodyType.Selected,
+			BodyFile:    bodyFile.Text,
+			RawLanguage: rawLanguage.Selected,
+			Url:         urlBase(url.Text),
+			Title:       title,
+			RestMethod:  restMethod.Selected,
+			SslEnabled:  sslCheckbox.Checked,
+			Timeouts:    getTimeouts(),
+			Auth:        getAuth(),
+			Tls:         getTls(),
+			Proxy:       getProxy(),
+			Path:        tabPath,
 		}
+	}
 
-		// set headers
-		for _, line := range strings.Split(headers.Text, "\n") {
-			if line == "" || line[0] == '#' {
-				continue
-			}
-			key, value, found := strings.Cut(line, "\t")
-			if found {
-				if key != "" && validRunes(key) && validRunes(value) {
-					req.Header.Set(key, value)
-				} else {
-					errorPopUp(canvas, errors.New(fmt.Sprint("Error with header entry: ", key, "=", value)))
-					return
-				}
+	refreshImpliedHeaders = func() {
+		impliedHeadersLabel.SetText(impliedHeaders(currentVdatRequest("")))
+	}
+	refreshImpliedHeaders()
+	headers.OnChanged = func(string) {
+		headersEditor.refresh()
+		refreshImpliedHeaders()
+	}
+	bodyFile.OnChanged = func(string) { refreshImpliedHeaders() }
+
+	urlPreview := widget.NewLabel("")
+	urlPreview.TextStyle.Monospace = true
+	urlPreview.Truncation = fyne.TextTruncateEllipsis
+	refreshUrlPreview = func() {
+		vdatRequest := currentVdatRequest("")
+		environment, err := windowCallbacks.environmentCallback()
+		if err == nil {
+			substituted, err := applyEnvironment(vdatRequest, environment)
+			if err == nil {
+				vdatRequest = substituted
 			}
 		}
-
-		// send request
-		var client *http.Client
-		if sslCheckbox.Checked {
-			client = &http.Client{}
-		} else {
-			client = &http.Client{
-				Transport: &http.Transport{
-					TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-				},
-			}
+		finalUrl, err := substitutePathParams(vdatRequest.Url, vdatRequest.PathParams)
+		if err == nil {
+			finalUrl, err = buildRequestUrl(finalUrl, vdatRequest.Params)
+		}
+		if err == nil {
+			finalUrl, err = addAuthQuery(finalUrl, vdatRequest.Auth)
 		}
-
-		start := time.Now()
-		resp, err := client.Do(req)
-		elapsed := time.Since(start)
-		responseTime.SetText(elapsed.String())
 		if err != nil {
-			errorPopUp(canvas, err)
+			urlPreview.SetText(err.Error())
 			return
 		}
-		defer resp.Body.Close()
+		urlPreview.SetText(finalUrl)
+	}
+	// keep the query in the url entry and the params in sync
+	syncingParams := false
+	tlsHost := ""
+	url.OnChanged = func(text string) {
+		// show the settings saved for the host as placeholders
+		if host := urlHostname(text); host != tlsHost {
+			tlsHost = host
+			hostTls, _ := loadHostTls(host)
+			setTlsPlaceholders(hostTls)
+		}
+		pathParamsText := formatKeyValueRows(syncPathParamRows(parseKeyValueRows(pathParams.Text, "="), findPathParams(text)), "=")
+		if pathParamsText != pathParams.Text {
+			pathParams.SetText(pathParamsText)
+		}
+		if !syncingParams {
+			_, query := splitUrlQuery(text)
+			rows := mergeQueryRows(parseKeyValueRows(params.Text, "="), readableQueryPairs(query))
+			syncingParams = true
+			params.SetText(formatKeyValueRows(rows, "="))
+			syncingParams = false
+		}
+		refreshUrlPreview()
+	}
+	pathParams.OnChanged = func(string) {
+		pathParamsEditor.refresh()
+		refreshUrlPreview()
+	}
+	params.OnChanged = func(text string) {
+		paramsEditor.refresh()
+		if !syncingParams {
+			syncingParams = true
+			url.SetText(composeUrl(urlBase(url.Text), parseKeyValueRows(text, "=")))
+			syncingParams = false
+		}
+		refreshUrlPreview()
+	}
+
+	var sendButton *widget.Button
+	cancelButton := widget.NewButton(CANCEL_BUTTON_TEXT, func() {
+		if cancelSend != nil {
+			cancelSend()
+		}
+	})
+	cancelButton.Disable()
+
+	sendButton = widget.NewButton(SEND_BUTTON_TEXT, func() {
+		responseBody.SetText("")
+		responseHeaders.SetText("")
+		responseCookies.SetText("")
+		responseInfo.SetText("")
+		responseTiming.SetText("")
+		responseTls.SetText("")
+		responseStatus.SetText("")
+		responseTime.SetText("")
 
-		// read response
-		responseBodyContent, err := io.ReadAll(resp.Body)
+		environment, err := windowCallbacks.environmentCallback()
 		if err != nil {
 			errorPopUp(canvas, err)
 			return
 		}
 
-		// report response
-		responseStatus.SetText(resp.Status)
-		responseBody.SetText(smartFormat(responseBodyContent))
+		refreshUrlPreview()
+		vdatRequest := currentVdatRequest("")
+		settings := windowCallbacks.settingsCallback()
+		ctx, cancel := context.WithCancel(context.Background())
+		cancelSend = cancel
+		sendButton.Disable()
+		cancelButton.Enable()
+		sendProgress.Show()
+		responseStatus.SetText(SENDING_TEXT)
+
+		go func() {
+			defer func() {
+				cancel()
+				sendProgress.Hide()
+				cancelButton.Disable()
+				sendButton.Enable()
+			}()
+
+			vdatResponse, err := sendVdatRequest(ctx, vdatRequest, environment, settings)
+			if vdatResponse.Elapsed != 0 {
+				responseTime.SetText(vdatResponse.Elapsed.String())
+				responseTiming.SetText(formatTiming(vdatResponse.Timing))
+			}
+			if errors.Is(err, context.Canceled) {
+				responseStatus.SetText(CANCELLED_TEXT)
+				return
+			}
+			if err != nil {
+				responseStatus.SetText("")
+				errorPopUp(canvas, err)
+				return
+			}
+
+			// report response
+			responseStatus.SetText(vdatResponse.Response.Status)
+			responseBody.SetText(smartFormat(vdatResponse.Body))
+			responseHeaders.SetText(formatResponseHeaders(vdatResponse.Response))
+			responseCookies.SetText(formatResponseCookies(vdatResponse.Response))
+			responseInfo.SetText(formatResponseInfo(vdatResponse))
+			responseTls.SetText(formatResponseTls(vdatResponse.Response, vdatRequest.SslEnabled, time.Now()))
+		}()
 	})
-	controls := container.NewBorder(nil, nil, restMethod, container.NewHBox(sslCheckbox, sendButton), url)
+	controls := container.NewBorder(nil, nil, restMethod, container.NewHBox(sendButton, cancelButton), url)
 
 	requestPane := container.NewAppTabs(
-		container.NewTabItem(TABS_PARAMS, params),
-		container.NewTabItem(TABS_HEADERS, headers),
-		container.NewTabItem(TABS_BODY, bodyPane))
-	responsePane := container.NewBorder(container.NewVBox(responseStatus, responseTime), nil, nil, nil, responseBody)
+		container.NewTabItem(TABS_PARAMS, paramsEditor.content),
+		container.NewTabItem(TABS_PATH_PARAMS, pathParamsEditor.content),
+		container.NewTabItem(TABS_AUTH, authForm),
+		container.NewTabItem(TABS_HEADERS, container.NewBorder(nil, impliedHeadersLabel, nil, nil, headersEditor.content)),
+		container.NewTabItem(TABS_BODY, bodyPane),
+		container.NewTabItem(TABS_TLS, container.NewVScroll(container.NewVBox(sslCheckbox, tlsForm, container.NewHBox(tlsHostButton)))),
+		container.NewTabItem(TABS_SETTINGS, container.NewVScroll(container.NewVBox(timeoutsForm, proxyForm))))
+	responseTabs := container.NewAppTabs(
+		container.NewTabItem(TABS_BODY, responseBody),
+		container.NewTabItem(TABS_HEADERS, responseHeaders),
+		container.NewTabItem(TABS_COOKIES, responseCookies),
+		container.NewTabItem(TABS_INFO, responseInfo),
+		container.NewTabItem(TABS_TIMING, responseTiming),
+		container.NewTabItem(TABS_TLS, responseTls))
+	responsePane := container.NewBorder(container.NewVBox(sendProgress, responseStatus, responseTime), nil, nil, nil, responseTabs)
 	requestAndResponse := container.NewHSplit(requestPane, responsePane)
 
-	content := container.NewBorder(controls, nil, nil, nil, requestAndResponse)
+	content := container.NewBorder(container.NewVBox(controls, urlPreview), nil, nil, nil, requestAndResponse)
 
 	saveCallback := func(dirname string, title string) error {
-		vdatRequest := VdatRequest{
-			Headers:     headers.Text,
-			Params:      params.Text,
-			BodyContent: bodyContent.Text,
-			BodyType:    bodyType.Selected,
-			Url:         url.Text,
-			Title:       title,
-			RestMethod:  restMethod.Selected,
-			SslEnabled:  sslCheckbox.Checked,
-		}
+		vdatRequest := currentVdatRequest(title)
 
 		// Create a file to save the struct
 		filename := filepath.Join(dirname, fmt.Sprint(restMethod.Selected, " - ", title))
 		tabPath = filename
-		file, err := os.Create(filename)
-		if err != nil {
-			return err
-		}
-		defer file.Close()
-
-		// Serialize the struct to JSON
-		encoder := json.NewEncoder(file)
-		err = encoder.Encode(vdatRequest)
-		return err
+		return saveVdatRequest(filename, vdatRequest)
 	}
 
-	loadCallback := func(filename string) (string, error) {
-		file, err := os.Open(filename)
-		if err != nil {
-			return "", err
+	// showCallback fills the tab without a saved file, like an imported
+	// request that only gets a path once it is saved
+	showCallback := func(vdatRequest VdatRequest) string {
+		headers.SetText(vdatRequest.Headers)
+		pathParams.SetText(vdatRequest.PathParams)
+		syncingParams = true
+		base, query := splitUrlQuery(vdatRequest.Url)
+		paramsRows := parseKeyValueRows(vdatRequest.Params, "=")
+		for _, pair := range readableQueryPairs(query) {
+			paramsRows = append(paramsRows, KeyValueRow{KeyValue: pair, Enabled: true})
 		}
-		tabPath = filename
-		defer file.Close()
-
-		// Create an instance of the struct to load data into
-		vdatRequest := VdatRequest{}
-
-		// Create a JSON decoder and decode the file content into the struct
-		decoder := json.NewDecoder(file)
-		err = decoder.Decode(&vdatRequest)
-		if err != nil {
-			return "", err
+		if query != "" {
+			params.SetText(formatKeyValueRows(paramsRows, "="))
+		} else {
+			params.SetText(vdatRequest.Params)
 		}
-
-		headers.SetText(vdatRequest.Headers)
-		params.SetText(vdatRequest.Params)
+		url.SetText(composeUrl(base, paramsRows))
+		syncingParams = false
 		bodyContent.SetText(vdatRequest.BodyContent)
+		bodyFile.SetText(vdatRequest.BodyFile)
+		// SetSelected ignores a value that is not an option, a raw body
+		// without a language is sent without a Content-Type
+		if containsString(RAW_LANGUAGES, vdatRequest.RawLanguage) {
+			rawLanguage.SetSelected(vdatRequest.RawLanguage)
+		} else {
+			rawLanguage.ClearSelected()
+		}
 		bodyType.SetSelected(vdatRequest.BodyType)
-		url.SetText(vdatRequest.Url)
 		restMethod.SetSelected(vdatRequest.RestMethod)
 		sslCheckbox.SetChecked(vdatRequest.SslEnabled)
+		setTimeouts(vdatRequest.Timeouts)
+		setAuth(vdatRequest.Auth)
+		setTls(vdatRequest.Tls)
+		setProxy(vdatRequest.Proxy)
+		return vdatRequest.Title
+	}
 
-		return vdatRequest.Title, nil
+	loadCallback := func(filename string) (string, error) {
+		vdatRequest, err := loadVdatRequest(filename)
+		if err != nil {
+			return "", err
+		}
+		tabPath = filename
+		return showCallback(vdatRequest), nil
 	}
 
 	pathCallback := func() string {
 		return tabPath
 	}
 
+	requestCallback := func() VdatRequest {
+		return currentVdatRequest("")
+	}
+
 	tabCallbacks := TabCallbacks{
-		saveCallback: saveCallback,
-		loadCallback: loadCallback,
-		pathCallback: pathCallback,
+		saveCallback:    saveCallback,
+		loadCallback:    loadCallback,
+		showCallback:    showCallback,
+		pathCallback:    pathCallback,
+		requestCallback: requestCallback,
 	}
 
 	return content, tabCallbacks
 }
 
 func main() {
-	vdatApp := app.New()
+	if len(os.Args) > 1 && os.Args[1] == RUN_COMMAND {
+		os.Exit(runCommand(os.Args[2:], os.Stdout, os.Stderr))
+	}
+
+	vdatApp := app.NewWithID(APP_ID)
 	var err error
 
 	// Load the icon from a file
@@ -623,6 +674,11 @@ func main() {
 	tabs := container.NewAppTabs()
 	tabTitle := widget.NewEntry()
 	tabCallbackMap := make(map[*container.TabItem]TabCallbacks)
+	var windowCallbacks WindowCallbacks
+	settings := loadSettings(vdatApp.Preferences())
+	windowCallbacks.settingsCallback = func() VdatSettings {
+		return settings
+	}
 
 	tree := widget.NewTree(
 		func(id widget.TreeNodeID) (children []widget.TreeNodeID) {
@@ -666,7 +722,7 @@ func main() {
 				}
 			}
 
-			newTabContent, tabCallbacks := makeNewTabContent(vdatWindow.Canvas())
+			newTabContent, tabCallbacks := makeNewTabContent(vdatWindow, windowCallbacks)
 			title, err := tabCallbacks.loadCallback(treeSelected)
 			if err != nil {
 				errorPopUp(vdatWindow.Canvas(), errors.New(fmt.Sprint("Failed to load file: ", treeSelected)))
@@ -682,6 +738,20 @@ func main() {
 	// Create a scrollable container for the tree
 	fileTree := container.NewScroll(tree)
 
+	showCurlCommand := func(vdatRequest VdatRequest) {
+		environment, err := windowCallbacks.environmentCallback()
+		if err != nil {
+			errorPopUp(vdatWindow.Canvas(), err)
+			return
+		}
+		curlCommand, err := exportCurlCommand(vdatRequest, environment)
+		if err != nil {
+			errorPopUp(vdatWindow.Canvas(), err)
+			return
+		}
+		copyTextPopUp(vdatWindow, CURL_EXPORT_TEXT, curlCommand)
+	}
+
 	deleteButton := widget.NewButton("DELETE", func() {
 		resultCh := confirmationPopup(vdatWindow.Canvas(), fmt.Sprint("Are you sure you want to delete: ", treeSelected))
 		go func() {
@@ -712,8 +782,158 @@ func main() {
 			tree.RefreshItem(treeSelectedFolder)
 		}()
 	})
-	fileControls := container.NewBorder(nil, nil, nil, deleteButton, newFolderButton)
-	filePane := container.NewBorder(fileControls, nil, nil, nil, fileTree)
+	exportCurlFileButton := widget.NewButton(EXPORT_CURL_BUTTON_TEXT, func() {
+		isDir, err := checkDirExists(treeSelected)
+		if err != nil {
+			errorPopUp(vdatWindow.Canvas(), err)
+			return
+		}
+		if isDir {
+			errorPopUp(vdatWindow.Canvas(), errors.New("Select a request to copy as curl"))
+			return
+		}
+		vdatRequest, err := loadVdatRequest(treeSelected)
+		if err != nil {
+			errorPopUp(vdatWindow.Canvas(), err)
+			return
+		}
+		showCurlCommand(vdatRequest)
+	})
+
+	environmentSelect := widget.NewSelect([]string{}, nil)
+	environmentSelect.PlaceHolder = ENVIRONMENT_PLACEHOLDER
+	refreshEnvironments := func(selected string) {
+		names, err := listEnvironments()
+		if err != nil {
+			errorPopUp(vdatWindow.Canvas(), err)
+			return
+		}
+		environmentSelect.Options = append([]string{NO_ENVIRONMENT_TEXT}, names...)
+		environmentSelect.SetSelected(selected)
+	}
+	refreshEnvironments(NO_ENVIRONMENT_TEXT)
+	windowCallbacks.environmentCallback = func() (VdatEnvironment, error) {
+		if environmentSelect.Selected == NO_ENVIRONMENT_TEXT {
+			return VdatEnvironment{}, nil
+		}
+		return loadEnvironment(environmentSelect.Selected)
+	}
+
+	editEnvironment := func(environment VdatEnvironment) {
+		resultCh := getMultilineStringPopUp(vdatWindow.Canvas(), fmt.Sprint("Variables for environment: ", environment.Name), environment.Variables)
+		go func() {
+			environment.Variables = <-resultCh
+			err := saveEnvironment(environment)
+			if err != nil {
+				errorPopUp(vdatWindow.Canvas(), err)
+				return
+			}
+			refreshEnvironments(environment.Name)
+		}()
+	}
+	newEnvironmentButton := widget.NewButton(NEW_ENVIRONMENT_BUTTON_TEXT, func() {
+		resultCh := getStringPopUp(vdatWindow.Canvas(), "New Environment Name")
+		go func() {
+			name := <-resultCh
+			editEnvironment(VdatEnvironment{Name: name})
+		}()
+	})
+	editEnvironmentButton := widget.NewButton(EDIT_ENVIRONMENT_BUTTON_TEXT, func() {
+		environment, err := windowCallbacks.environmentCallback()
+		if err != nil {
+			errorPopUp(vdatWindow.Canvas(), err)
+			return
+		}
+		if environment.Name == "" {
+			errorPopUp(vdatWindow.Canvas(), errors.New("No environment selected"))
+			return
+		}
+		editEnvironment(environment)
+	})
+	environmentControls := container.NewBorder(nil, nil, nil, container.NewHBox(newEnvironmentButton, editEnvironmentButton), environmentSelect)
+
+	importCollectionButton := widget.NewButton(IMPORT_COLLECTION_BUTTON_TEXT, func() {
+		parent := treeSelectedFolder
+		dialog.ShowFileOpen(func(reader fyne.URIReadCloser, err error) {
+			if err != nil {
+				errorPopUp(vdatWindow.Canvas(), err)
+				return
+			}
+			if reader == nil {
+				return
+			}
+			reader.Close()
+			collection, err := importCollection(reader.URI().Path(), parent)
+			if collection != nil && len(collection.folders) != 0 {
+				tree.RefreshItem(parent)
+				refreshEnvironments(environmentSelect.Selected)
+			}
+			if err != nil {
+				errorPopUp(vdatWindow.Canvas(), err)
+				return
+			}
+			messagePopUp(vdatWindow.Canvas(), collection.summary())
+		}, vdatWindow)
+	})
+	exportPostman := func(folder string, environment VdatEnvironment) {
+		content, warnings, err := exportPostmanCollection(folder, environment)
+		if err != nil {
+			errorPopUp(vdatWindow.Canvas(), err)
+			return
+		}
+		saveDialog := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+			if err != nil {
+				errorPopUp(vdatWindow.Canvas(), err)
+				return
+			}
+			if writer == nil {
+				return
+			}
+			defer writer.Close()
+			_, err = writer.Write(content)
+			if err != nil {
+				errorPopUp(vdatWindow.Canvas(), err)
+				return
+			}
+			if len(warnings) != 0 {
+				messagePopUp(vdatWindow.Canvas(), COLLECTION_WARNINGS_TEXT+"\n"+strings.Join(warnings, "\n"))
+			}
+		}, vdatWindow)
+		saveDialog.SetFileName(filepath.Base(folder) + POSTMAN_EXTENSION)
+		saveDialog.Show()
+	}
+	exportPostmanButton := widget.NewButton(EXPORT_POSTMAN_BUTTON_TEXT, func() {
+		folder := treeSelectedFolder
+		environment, err := windowCallbacks.environmentCallback()
+		if err != nil {
+			errorPopUp(vdatWindow.Canvas(), err)
+			return
+		}
+		// environments hold tokens and passwords, they are only exported
+		// when asked for
+		names := []string{}
+		for _, row := range parseKeyValueRows(environment.Variables, "=") {
+			if row.Key != "" {
+				names = append(names, strings.TrimSpace(row.Key))
+			}
+		}
+		if len(names) == 0 {
+			exportPostman(folder, VdatEnvironment{})
+			return
+		}
+		resultCh := confirmationPopup(vdatWindow.Canvas(), fmt.Sprint(POSTMAN_VARIABLES_TEXT, "\n", environment.Name, ": ", strings.Join(names, ", ")))
+		go func() {
+			if !<-resultCh {
+				environment = VdatEnvironment{}
+			}
+			exportPostman(folder, environment)
+		}()
+	})
+	fileControls := container.NewVBox(
+		container.NewBorder(nil, nil, nil, container.NewHBox(exportCurlFileButton, deleteButton), newFolderButton),
+		container.NewHBox(importCollectionButton, exportPostmanButton))
+
+	filePane := container.NewBorder(container.NewVBox(fileControls, environmentControls), nil, nil, nil, fileTree)
 
 	tabTitle.SetPlaceHolder(TITLE_PLACEHOLDER)
 
@@ -745,43 +965,38 @@ func main() {
 		}
 	}
 	importButton := widget.NewButton(IMPORT_BUTTON_TEXT, func() {
-		resultCh := getMultilineStringPopUp(vdatWindow.Canvas(), "Paste your curl command here")
+		resultCh := getMultilineStringPopUp(vdatWindow.Canvas(), "Paste your curl command here", "")
 		go func() {
 			curlCommand := <-resultCh
 
-			tempFile, err := os.CreateTemp("", "vdat-*")
+			vdatRequest, warnings, err := parseCurlCommand(curlCommand)
 			if err != nil {
 				errorPopUp(vdatWindow.Canvas(), err)
 				return
 			}
-			defer os.Remove(tempFile.Name())
 
-			vdatRequest, err := parseCurlCommand(curlCommand)
-			if err != nil {
-				errorPopUp(vdatWindow.Canvas(), err)
-				return
-			}
-
-			// Serialize the struct to JSON
-			encoder := json.NewEncoder(tempFile)
-			err = encoder.Encode(vdatRequest)
-			if err != nil {
-				errorPopUp(vdatWindow.Canvas(), err)
-				return
-			}
-
-			newTabContent, tabCallbacks := makeNewTabContent(vdatWindow.Canvas())
-			title, err := tabCallbacks.loadCallback(tempFile.Name())
-			if err != nil {
-				errorPopUp(vdatWindow.Canvas(), errors.New(fmt.Sprint("Failed to load file: ", tempFile.Name())))
-				return
-			}
+			newTabContent, tabCallbacks := makeNewTabContent(vdatWindow, windowCallbacks)
+			title := tabCallbacks.showCallback(vdatRequest)
 			newTab := container.NewTabItem(title, newTabContent)
 			tabCallbackMap[newTab] = tabCallbacks
 			tabs.Append(newTab)
 			tabs.Select(newTab)
+			if len(warnings) != 0 {
+				messagePopUp(vdatWindow.Canvas(), fmt.Sprint(CURL_WARNINGS_TEXT, "\n", strings.Join(warnings, "\n")))
+			}
 		}()
 	})
+	exportCurlButton := widget.NewButton(EXPORT_CURL_BUTTON_TEXT, func() {
+		showCurlCommand(tabCallbackMap[tabs.Selected()].requestCallback())
+	})
+	codegenButton := widget.NewButton(CODEGEN_BUTTON_TEXT, func() {
+		environment, err := windowCallbacks.environmentCallback()
+		if err != nil {
+			errorPopUp(vdatWindow.Canvas(), err)
+			return
+		}
+		codegenPopUp(vdatWindow, tabCallbackMap[tabs.Selected()].requestCallback(), environment)
+	})
 	saveButton := widget.NewButton(SAVE_BUTTON_TEXT, func() {
 		err := tabCallbackMap[tabs.Selected()].saveCallback(treeSelectedFolder, tabTitle.Text)
 		if err != nil {
@@ -793,7 +1008,7 @@ func main() {
 
 	})
 	newTabButton := widget.NewButton(NEW_BUTTON_TEXT, func() {
-		newTabContent, tabCallbacks := makeNewTabContent(vdatWindow.Canvas())
+		newTabContent, tabCallbacks := makeNewTabContent(vdatWindow, windowCallbacks)
 		newTab := container.NewTabItem(TITLE_DEFAULT, newTabContent)
 		tabCallbackMap[newTab] = tabCallbacks
 		tabs.Append(newTab)
@@ -805,7 +1020,13 @@ func main() {
 			doSelectTab()
 		}
 	})
-	tabControlButtons := container.NewHBox(importButton, saveButton, newTabButton, closeTabButton)
+	settingsButton := widget.NewButton(SETTINGS_BUTTON_TEXT, func() {
+		settingsPopUp(vdatWindow.Canvas(), settings, func(result VdatSettings) {
+			settings = result
+			saveSettings(vdatApp.Preferences(), settings)
+		})
+	})
+	tabControlButtons := container.NewHBox(importButton, exportCurlButton, codegenButton, saveButton, newTabButton, closeTabButton, settingsButton)
 	tabControls := container.NewBorder(nil, nil, nil, tabControlButtons, tabTitle)
 
 	tabsWithControls := container.NewBorder(tabControls, nil, nil, nil, tabs)
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"testing"
+
+	"fyne.io/fyne/v2/test"
+)
+
+func TestShowRawLanguage(t *testing.T) {
+	useTempVdatDir(t)
+	test.NewApp()
+	window := test.NewWindow(nil)
+	defer window.Close()
+	windowCallbacks := WindowCallbacks{
+		environmentCallback: func() (VdatEnvironment, error) { return VdatEnvironment{}, nil },
+		settingsCallback:    defaultSettings,
+	}
+
+	for _, language := range []string{"", RAW_LANGUAGE_XML, "Unknown"} {
+		_, tabCallbacks := makeNewTabContent(window, windowCallbacks)
+		tabCallbacks.showCallback(VdatRequest{BodyType: BODY_TYPE_RAW, RawLanguage: language, RestMethod: "POST"})
+		want := language
+		if language == "Unknown" {
+			want = ""
+		}
+		got := tabCallbacks.requestCallback().RawLanguage
+		if got != want {
+			t.Errorf("RawLanguage %q shown as %q, want %q", language, got, want)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type VdatMultipartPart struct {
+	Name        string
+	Value       string
+	IsFile      bool
+	FromFile    bool
+	ContentType string
+	Filename    string
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func partDisposition(name string, filename string) string {
+	disposition := fmt.Sprintf(`form-data; name="%s"`, quoteEscaper.Replace(name))
+	if filename != "" {
+		disposition += fmt.Sprintf(`; filename="%s"`, quoteEscaper.Replace(filename))
+	}
+	return disposition
+}
+
+// parseMultipartPart parses a single part written in curl's -F syntax:
+// name=value, name=@file or name=<file, optionally followed by
+// ;type=content/type and ;filename=name.
+func parseMultipartPart(line string) (VdatMultipartPart, error) {
+	name, value, found := strings.Cut(line, "=")
+	if !found || name == "" {
+		return VdatMultipartPart{}, errors.New(fmt.Sprint("Error with multipart entry: ", line))
+	}
+	part := VdatMultipartPart{Name: name}
+
+	if strings.HasPrefix(value, "@") {
+		part.IsFile = true
+		value = value[1:]
+	} else if strings.HasPrefix(value, "<") {
+		part.FromFile = true
+		value = value[1:]
+	}
+
+	// peel ;type= and ;filename= attributes off the end of the value
+	for {
+		index := strings.LastIndex(value, ";")
+		if index < 0 {
+			break
+		}
+		attributeKey, attributeValue, _ := strings.Cut(value[index+1:], "=")
+		switch strings.ToLower(strings.TrimSpace(attributeKey)) {
+		case "type":
+			part.ContentType = attributeValue
+		case "filename":
+			part.Filename = strings.Trim(attributeValue, "\"")
+		default:
+			index = -1
+		}
+		if index < 0 {
+			break
+		}
+		value = value[:index]
+	}
+	part.Value = value
+
+	if (part.IsFile || part.FromFile) && part.Value == "" {
+		return VdatMultipartPart{}, errors.New(fmt.Sprint("Missing file for multipart entry: ", line))
+	}
+	return part, nil
+}
+
+func parseMultipartParts(text string) ([]VdatMultipartPart, error) {
+	parts := []VdatMultipartPart{}
+	for _, line := range strings.Split(text, "\n") {
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		part, err := parseMultipartPart(line)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, part)
+	}
+	return parts, nil
+}
+
+func (part VdatMultipartPart) String() string {
+	text := part.Name + "="
+	if part.IsFile {
+		text += "@"
+	} else if part.FromFile {
+		text += "<"
+	}
+	text += part.Value
+	if part.ContentType != "" {
+		text += ";type=" + part.ContentType
+	}
+	if part.Filename != "" {
+		text += ";filename=" + part.Filename
+	}
+	return text
+}
+
+func buildMultipartBody(text string, requestPath string) (io.Reader, string, error) {
+	parts, err := parseMultipartParts(text)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var buffer bytes.Buffer
+	writer := multipart.NewWriter(&buffer)
+	for _, part := range parts {
+		header := make(textproto.MIMEHeader)
+		contentType := part.ContentType
+		filename := part.Filename
+
+		var content io.Reader = strings.NewReader(part.Value)
+		if part.IsFile || part.FromFile {
+			path, err := resolveBodyFilePath(part.Value, requestPath)
+			if err != nil {
+				return nil, "", err
+			}
+			file, err := os.Open(path)
+			if err != nil {
+				return nil, "", err
+			}
+			defer file.Close()
+			content = file
+
+			if part.IsFile {
+				if filename == "" {
+					filename = filepath.Base(path)
+				}
+				if contentType == "" {
+					contentType = mime.TypeByExtension(filepath.Ext(path))
+				}
+				if contentType == "" {
+					contentType = "application/octet-stream"
+				}
+			}
+		}
+
+		header.Set("Content-Disposition", partDisposition(part.Name, filename))
+		if contentType != "" {
+			header.Set("Content-Type", contentType)
+		}
+
+		partWriter, err := writer.CreatePart(header)
+		if err != nil {
+			return nil, "", err
+		}
+		_, err = io.Copy(partWriter, content)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+	err = writer.Close()
+	if err != nil {
+		return nil, "", err
+	}
+	return &buffer, writer.FormDataContentType(), nil
+}
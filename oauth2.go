@@ -0,0 +1,350 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+type VdatToken struct {
+	AccessToken  string    `json:"AccessToken"`
+	TokenType    string    `json:"TokenType"`
+	RefreshToken string    `json:"RefreshToken"`
+	Expiry       time.Time `json:"Expiry"`
+}
+
+type tokenResponse struct {
+	AccessToken      string      `json:"access_token"`
+	TokenType        string      `json:"token_type"`
+	ExpiresIn        json.Number `json:"expires_in"`
+	RefreshToken     string      `json:"refresh_token"`
+	Error            string      `json:"error"`
+	ErrorDescription string      `json:"error_description"`
+}
+
+// tokens are cached in one file, keyed by environment and then by the
+// settings that identify the token
+var tokenCacheMutex sync.Mutex
+
+func (token VdatToken) valid() bool {
+	if token.AccessToken == "" {
+		return false
+	}
+	return token.Expiry.IsZero() || time.Now().Add(OAUTH2_EXPIRY_MARGIN).Before(token.Expiry)
+}
+
+func tokenCacheKey(auth VdatAuth) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{auth.Grant, auth.TokenUrl, auth.AuthUrl, auth.ClientId, auth.Scope, auth.Username}, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+func getTokensPath() (string, error) {
+	vdatDir, err := getVdatDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(vdatDir, TOKENS_FILE), nil
+}
+
+func readTokenCache() (map[string]map[string]VdatToken, error) {
+	cache := make(map[string]map[string]VdatToken)
+	tokensPath, err := getTokensPath()
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(tokensPath)
+	if os.IsNotExist(err) {
+		return cache, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(content, &cache)
+	if err != nil {
+		// a broken cache only costs a new token
+		return make(map[string]map[string]VdatToken), nil
+	}
+	return cache, nil
+}
+
+func writeTokenCache(cache map[string]map[string]VdatToken) error {
+	tokensPath, err := getTokensPath()
+	if err != nil {
+		return err
+	}
+	content, err := json.Marshal(cache)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(tokensPath, content, 0600)
+}
+
+func loadCachedToken(environmentName string, auth VdatAuth) (VdatToken, bool) {
+	tokenCacheMutex.Lock()
+	defer tokenCacheMutex.Unlock()
+	cache, err := readTokenCache()
+	if err != nil {
+		return VdatToken{}, false
+	}
+	token, found := cache[environmentName][tokenCacheKey(auth)]
+	return token, found
+}
+
+func saveCachedToken(environmentName string, auth VdatAuth, token VdatToken) error {
+	tokenCacheMutex.Lock()
+	defer tokenCacheMutex.Unlock()
+	cache, err := readTokenCache()
+	if err != nil {
+		return err
+	}
+	if cache[environmentName] == nil {
+		cache[environmentName] = make(map[string]VdatToken)
+	}
+	cache[environmentName][tokenCacheKey(auth)] = token
+	return writeTokenCache(cache)
+}
+
+func clearCachedToken(environmentName string, auth VdatAuth) error {
+	tokenCacheMutex.Lock()
+	defer tokenCacheMutex.Unlock()
+	cache, err := readTokenCache()
+	if err != nil {
+		return err
+	}
+	delete(cache[environmentName], tokenCacheKey(auth))
+	return writeTokenCache(cache)
+}
+
+// getOAuth2Token returns the cached token for the environment, refreshing or
+// fetching a new one when it has expired.
+func getOAuth2Token(ctx context.Context, client *http.Client, auth VdatAuth, environmentName string, open func(string) error) (VdatToken, error) {
+	cached, found := loadCachedToken(environmentName, auth)
+	if found && cached.valid() {
+		return cached, nil
+	}
+
+	var token VdatToken
+	var err error
+	if found && cached.RefreshToken != "" {
+		token, err = requestToken(ctx, client, auth, url.Values{
+			"grant_type":    {"refresh_token"},
+			"refresh_token": {cached.RefreshToken},
+		})
+	}
+	if !found || cached.RefreshToken == "" || err != nil {
+		token, err = fetchOAuth2Token(ctx, client, auth, open)
+	}
+	if err != nil {
+		return VdatToken{}, err
+	}
+
+	// servers may leave out the refresh token when it does not change
+	if token.RefreshToken == "" && found {
+		token.RefreshToken = cached.RefreshToken
+	}
+	err = saveCachedToken(environmentName, auth, token)
+	return token, err
+}
+
+func fetchOAuth2Token(ctx context.Context, client *http.Client, auth VdatAuth, open func(string) error) (VdatToken, error) {
+	form := url.Values{}
+	if auth.Scope != "" {
+		form.Set("scope", auth.Scope)
+	}
+	switch auth.Grant {
+	case OAUTH2_GRANT_CLIENT_CREDENTIALS:
+		form.Set("grant_type", "client_credentials")
+	case OAUTH2_GRANT_PASSWORD:
+		form.Set("grant_type", "password")
+		form.Set("username", auth.Username)
+		form.Set("password", auth.Password)
+	case OAUTH2_GRANT_REFRESH_TOKEN:
+		form.Set("grant_type", "refresh_token")
+		form.Set("refresh_token", auth.RefreshToken)
+	case OAUTH2_GRANT_AUTHORIZATION_CODE:
+		return authorizeWithPkce(ctx, client, auth, open)
+	default:
+		return VdatToken{}, errors.New(fmt.Sprint("Unsupported OAuth 2.0 grant: ", auth.Grant))
+	}
+	return requestToken(ctx, client, auth, form)
+}
+
+func requestToken(ctx context.Context, client *http.Client, auth VdatAuth, form url.Values) (VdatToken, error) {
+	if auth.TokenUrl == "" {
+		return VdatToken{}, errors.New("Missing OAuth 2.0 token url")
+	}
+	// confidential clients authenticate with basic auth, public clients only name themselves
+	if auth.ClientSecret == "" && auth.ClientId != "" {
+		form.Set("client_id", auth.ClientId)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, auth.TokenUrl, strings.NewReader(form.Encode()))
+	if err != nil {
+		return VdatToken{}, err
+	}
+	req.Header.Set("Content-Type", CONTENT_TYPE_FORM)
+	req.Header.Set("Accept", "application/json")
+	if auth.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(auth.ClientId), url.QueryEscape(auth.ClientSecret))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return VdatToken{}, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return VdatToken{}, err
+	}
+
+	var response tokenResponse
+	err = json.Unmarshal(body, &response)
+	if response.Error != "" {
+		return VdatToken{}, errors.New(fmt.Sprint("OAuth 2.0 token error: ", response.Error, " ", response.ErrorDescription))
+	}
+	if resp.StatusCode >= 400 || err != nil || response.AccessToken == "" {
+		return VdatToken{}, errors.New(fmt.Sprint("OAuth 2.0 token request failed: ", resp.Status, " ", string(body)))
+	}
+
+	token := VdatToken{
+		AccessToken:  response.AccessToken,
+		TokenType:    response.TokenType,
+		RefreshToken: response.RefreshToken,
+	}
+	if seconds, err := response.ExpiresIn.Int64(); err == nil && seconds > 0 {
+		token.Expiry = time.Now().Add(time.Duration(seconds) * time.Second)
+	}
+	return token, nil
+}
+
+func randomUrlString(size int) (string, error) {
+	bytes := make([]byte, size)
+	_, err := rand.Read(bytes)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(bytes), nil
+}
+
+// authorizeWithPkce opens the authorization url in a browser and waits for
+// the redirect on a loopback listener before exchanging the code.
+func authorizeWithPkce(ctx context.Context, client *http.Client, auth VdatAuth, open func(string) error) (VdatToken, error) {
+	if auth.AuthUrl == "" {
+		return VdatToken{}, errors.New("Missing OAuth 2.0 authorization url")
+	}
+	verifier, err := randomUrlString(32)
+	if err != nil {
+		return VdatToken{}, err
+	}
+	state, err := randomUrlString(16)
+	if err != nil {
+		return VdatToken{}, err
+	}
+	challenge := sha256.Sum256([]byte(verifier))
+
+	listener, err := net.Listen("tcp", OAUTH2_REDIRECT_ADDRESS)
+	if err != nil {
+		return VdatToken{}, err
+	}
+	redirectUrl := fmt.Sprint("http://", listener.Addr().String(), OAUTH2_REDIRECT_PATH)
+
+	authUrl, err := url.Parse(auth.AuthUrl)
+	if err != nil {
+		listener.Close()
+		return VdatToken{}, err
+	}
+	query := authUrl.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", auth.ClientId)
+	query.Set("redirect_uri", redirectUrl)
+	query.Set("state", state)
+	query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	query.Set("code_challenge_method", "S256")
+	if auth.Scope != "" {
+		query.Set("scope", auth.Scope)
+	}
+	authUrl.RawQuery = query.Encode()
+
+	type callbackResult struct {
+		code string
+		err  error
+	}
+	resultCh := make(chan callbackResult, 1)
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != OAUTH2_REDIRECT_PATH {
+			http.NotFound(w, r)
+			return
+		}
+		values := r.URL.Query()
+		var result callbackResult
+		if values.Get("state") != state {
+			result.err = errors.New("OAuth 2.0 authorization failed: state mismatch")
+		} else if values.Get("error") != "" {
+			result.err = errors.New(fmt.Sprint("OAuth 2.0 authorization error: ", values.Get("error"), " ", values.Get("error_description")))
+		} else {
+			result.code = values.Get("code")
+		}
+		if result.err != nil {
+			http.Error(w, result.err.Error(), http.StatusBadRequest)
+		} else {
+			fmt.Fprint(w, OAUTH2_CALLBACK_TEXT)
+		}
+		select {
+		case resultCh <- result:
+		default:
+		}
+	})}
+	go server.Serve(listener)
+	defer server.Close()
+
+	err = open(authUrl.String())
+	if err != nil {
+		return VdatToken{}, errors.New(fmt.Sprint("Could not open a browser, visit ", authUrl.String(), " (", err, ")"))
+	}
+
+	timeout, cancel := context.WithTimeout(ctx, OAUTH2_AUTHORIZE_TIMEOUT)
+	defer cancel()
+	var result callbackResult
+	select {
+	case result = <-resultCh:
+	case <-timeout.Done():
+		return VdatToken{}, timeout.Err()
+	}
+	if result.err != nil {
+		return VdatToken{}, result.err
+	}
+
+	return requestToken(ctx, client, auth, url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {result.code},
+		"redirect_uri":  {redirectUrl},
+		"code_verifier": {verifier},
+	})
+}
+
+func openBrowser(urlText string) error {
+	switch runtime.GOOS {
+	case "darwin":
+		return exec.Command("open", urlText).Start()
+	case "windows":
+		return exec.Command("rundll32", "url.dll,FileProtocolHandler", urlText).Start()
+	default:
+		return exec.Command("xdg-open", urlText).Start()
+	}
+}
@@ -0,0 +1,386 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+// tokenServer answers token requests with respond and records the forms and
+// basic auth it was sent
+type tokenServer struct {
+	*httptest.Server
+	mutex    sync.Mutex
+	forms    []url.Values
+	users    []string
+	respond  func(form url.Values) (int, string)
+	requests int
+}
+
+func newTokenServer(t *testing.T, respond func(form url.Values) (int, string)) *tokenServer {
+	server := &tokenServer{respond: respond}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err := r.ParseForm()
+		if err != nil {
+			t.Error(err)
+		}
+		user, password, _ := r.BasicAuth()
+		server.mutex.Lock()
+		server.forms = append(server.forms, r.PostForm)
+		server.users = append(server.users, user+":"+password)
+		server.requests++
+		server.mutex.Unlock()
+		status, body := server.respond(r.PostForm)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func (server *tokenServer) lastForm() url.Values {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	return server.forms[len(server.forms)-1]
+}
+
+// useTempVdatDir keeps the token cache out of the real documents folder
+func useTempVdatDir(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_DOCUMENTS_DIR", dir)
+}
+
+func noBrowser(string) error {
+	return fmt.Errorf("no browser in tests")
+}
+
+func TestOAuth2ClientCredentials(t *testing.T) {
+	useTempVdatDir(t)
+	server := newTokenServer(t, func(form url.Values) (int, string) {
+		return http.StatusOK, `{"access_token": "cc-token", "token_type": "Bearer", "expires_in": 3600}`
+	})
+	auth := VdatAuth{
+		Type:         AUTH_TYPE_OAUTH2,
+		Grant:        OAUTH2_GRANT_CLIENT_CREDENTIALS,
+		TokenUrl:     server.URL,
+		ClientId:     "my client",
+		ClientSecret: "s3cret&",
+		Scope:        "read write",
+	}
+
+	token, err := getOAuth2Token(context.Background(), server.Client(), auth, "dev", noBrowser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.AccessToken != "cc-token" || token.TokenType != "Bearer" {
+		t.Errorf("token = %+v", token)
+	}
+	if time.Until(token.Expiry) < 59*time.Minute {
+		t.Errorf("Expiry = %v, want about an hour from now", token.Expiry)
+	}
+	form := server.lastForm()
+	if form.Get("grant_type") != "client_credentials" || form.Get("scope") != "read write" {
+		t.Errorf("form = %v", form)
+	}
+	if form.Has("client_id") {
+		t.Errorf("confidential client sent client_id in the form: %v", form)
+	}
+	// the client id and secret are form encoded before basic auth
+	if server.users[0] != "my+client:s3cret%26" {
+		t.Errorf("basic auth = %q", server.users[0])
+	}
+
+	// a valid cached token is used without asking the server
+	_, err = getOAuth2Token(context.Background(), server.Client(), auth, "dev", noBrowser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if server.requests != 1 {
+		t.Errorf("requests = %d, want the cached token to be used", server.requests)
+	}
+}
+
+func TestOAuth2Password(t *testing.T) {
+	useTempVdatDir(t)
+	server := newTokenServer(t, func(form url.Values) (int, string) {
+		return http.StatusOK, `{"access_token": "pw-token", "refresh_token": "pw-refresh"}`
+	})
+	auth := VdatAuth{
+		Type:     AUTH_TYPE_OAUTH2,
+		Grant:    OAUTH2_GRANT_PASSWORD,
+		TokenUrl: server.URL,
+		ClientId: "public",
+		Username: "alice",
+		Password: "p@ss word",
+	}
+
+	token, err := getOAuth2Token(context.Background(), server.Client(), auth, "dev", noBrowser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.AccessToken != "pw-token" || token.RefreshToken != "pw-refresh" || !token.Expiry.IsZero() {
+		t.Errorf("token = %+v", token)
+	}
+	form := server.lastForm()
+	if form.Get("grant_type") != "password" || form.Get("username") != "alice" || form.Get("password") != "p@ss word" {
+		t.Errorf("form = %v", form)
+	}
+	// public clients name themselves in the form instead of basic auth
+	if form.Get("client_id") != "public" || server.users[0] != ":" {
+		t.Errorf("form = %v, basic auth = %q", form, server.users[0])
+	}
+}
+
+func TestOAuth2Errors(t *testing.T) {
+	useTempVdatDir(t)
+	server := newTokenServer(t, func(form url.Values) (int, string) {
+		return http.StatusBadRequest, `{"error": "invalid_client", "error_description": "unknown client"}`
+	})
+	auth := VdatAuth{Type: AUTH_TYPE_OAUTH2, Grant: OAUTH2_GRANT_CLIENT_CREDENTIALS, TokenUrl: server.URL}
+	_, err := getOAuth2Token(context.Background(), server.Client(), auth, "dev", noBrowser)
+	if err == nil || err.Error() != "OAuth 2.0 token error: invalid_client unknown client" {
+		t.Errorf("err = %v", err)
+	}
+
+	auth.TokenUrl = ""
+	_, err = getOAuth2Token(context.Background(), server.Client(), auth, "dev", noBrowser)
+	if err == nil || err.Error() != "Missing OAuth 2.0 token url" {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestOAuth2Refresh(t *testing.T) {
+	auth := VdatAuth{
+		Type:     AUTH_TYPE_OAUTH2,
+		Grant:    OAUTH2_GRANT_CLIENT_CREDENTIALS,
+		ClientId: "client",
+	}
+	expired := VdatToken{AccessToken: "old", RefreshToken: "old-refresh", Expiry: time.Now().Add(-time.Minute)}
+
+	t.Run("refreshed", func(t *testing.T) {
+		useTempVdatDir(t)
+		server := newTokenServer(t, func(form url.Values) (int, string) {
+			return http.StatusOK, `{"access_token": "refreshed", "expires_in": "3600"}`
+		})
+		auth := auth
+		auth.TokenUrl = server.URL
+		err := saveCachedToken("dev", auth, expired)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		token, err := getOAuth2Token(context.Background(), server.Client(), auth, "dev", noBrowser)
+		if err != nil {
+			t.Fatal(err)
+		}
+		form := server.lastForm()
+		if server.requests != 1 || form.Get("grant_type") != "refresh_token" || form.Get("refresh_token") != "old-refresh" {
+			t.Errorf("requests = %d, form = %v", server.requests, form)
+		}
+		// the refresh token is kept when the server does not send a new one
+		if token.AccessToken != "refreshed" || token.RefreshToken != "old-refresh" {
+			t.Errorf("token = %+v", token)
+		}
+		cached, _ := loadCachedToken("dev", auth)
+		if cached.AccessToken != "refreshed" {
+			t.Errorf("cached = %+v", cached)
+		}
+	})
+
+	t.Run("fallback to a new token", func(t *testing.T) {
+		useTempVdatDir(t)
+		server := newTokenServer(t, func(form url.Values) (int, string) {
+			if form.Get("grant_type") == "refresh_token" {
+				return http.StatusBadRequest, `{"error": "invalid_grant"}`
+			}
+			return http.StatusOK, `{"access_token": "fresh", "refresh_token": "fresh-refresh"}`
+		})
+		auth := auth
+		auth.TokenUrl = server.URL
+		err := saveCachedToken("dev", auth, expired)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		token, err := getOAuth2Token(context.Background(), server.Client(), auth, "dev", noBrowser)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if server.requests != 2 || server.forms[0].Get("grant_type") != "refresh_token" || server.forms[1].Get("grant_type") != "client_credentials" {
+			t.Errorf("forms = %v", server.forms)
+		}
+		if token.AccessToken != "fresh" || token.RefreshToken != "fresh-refresh" {
+			t.Errorf("token = %+v", token)
+		}
+	})
+}
+
+func TestOAuth2Pkce(t *testing.T) {
+	useTempVdatDir(t)
+	var authorizeQuery url.Values
+	server := newTokenServer(t, func(form url.Values) (int, string) {
+		challenge := sha256.Sum256([]byte(form.Get("code_verifier")))
+		if base64.RawURLEncoding.EncodeToString(challenge[:]) != authorizeQuery.Get("code_challenge") {
+			return http.StatusBadRequest, `{"error": "invalid_grant", "error_description": "verifier mismatch"}`
+		}
+		return http.StatusOK, `{"access_token": "pkce-token"}`
+	})
+	auth := VdatAuth{
+		Type:     AUTH_TYPE_OAUTH2,
+		Grant:    OAUTH2_GRANT_AUTHORIZATION_CODE,
+		AuthUrl:  server.URL + "/authorize?audience=api",
+		TokenUrl: server.URL,
+		ClientId: "app",
+		Scope:    "openid",
+	}
+	// the browser is replaced by following the redirect with a code
+	open := func(authUrl string) error {
+		parsed, err := url.Parse(authUrl)
+		if err != nil {
+			return err
+		}
+		authorizeQuery = parsed.Query()
+		redirect := fmt.Sprint(authorizeQuery.Get("redirect_uri"), "?code=the-code&state=", url.QueryEscape(authorizeQuery.Get("state")))
+		go func() {
+			resp, err := http.Get(redirect)
+			if err == nil {
+				resp.Body.Close()
+			}
+		}()
+		return nil
+	}
+
+	token, err := getOAuth2Token(context.Background(), server.Client(), auth, "dev", open)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.AccessToken != "pkce-token" {
+		t.Errorf("token = %+v", token)
+	}
+	for key, want := range map[string]string{
+		"audience":              "api",
+		"response_type":         "code",
+		"client_id":             "app",
+		"scope":                 "openid",
+		"code_challenge_method": "S256",
+	} {
+		if authorizeQuery.Get(key) != want {
+			t.Errorf("authorize %s = %q, want %q", key, authorizeQuery.Get(key), want)
+		}
+	}
+	form := server.lastForm()
+	if form.Get("grant_type") != "authorization_code" || form.Get("code") != "the-code" || form.Get("redirect_uri") != authorizeQuery.Get("redirect_uri") {
+		t.Errorf("form = %v", form)
+	}
+}
+
+func TestOAuth2PkceStateMismatch(t *testing.T) {
+	useTempVdatDir(t)
+	server := newTokenServer(t, func(form url.Values) (int, string) {
+		return http.StatusOK, `{"access_token": "never"}`
+	})
+	auth := VdatAuth{Type: AUTH_TYPE_OAUTH2, Grant: OAUTH2_GRANT_AUTHORIZATION_CODE, AuthUrl: server.URL, TokenUrl: server.URL}
+	open := func(authUrl string) error {
+		parsed, _ := url.Parse(authUrl)
+		go func() {
+			resp, err := http.Get(parsed.Query().Get("redirect_uri") + "?code=x&state=forged")
+			if err == nil {
+				resp.Body.Close()
+			}
+		}()
+		return nil
+	}
+	_, err := getOAuth2Token(context.Background(), server.Client(), auth, "dev", open)
+	if err == nil || err.Error() != "OAuth 2.0 authorization failed: state mismatch" {
+		t.Errorf("err = %v", err)
+	}
+	if server.requests != 0 {
+		t.Errorf("requests = %d, want no code exchange", server.requests)
+	}
+}
+
+func TestOAuth2Cache(t *testing.T) {
+	useTempVdatDir(t)
+	server := newTokenServer(t, func(form url.Values) (int, string) {
+		return http.StatusOK, `{"access_token": "new"}`
+	})
+	auth := VdatAuth{Type: AUTH_TYPE_OAUTH2, Grant: OAUTH2_GRANT_CLIENT_CREDENTIALS, TokenUrl: server.URL, ClientId: "client"}
+	err := saveCachedToken("dev", auth, VdatToken{AccessToken: "dev-token"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// tokens are kept per environment
+	token, err := getOAuth2Token(context.Background(), server.Client(), auth, "dev", noBrowser)
+	if err != nil || token.AccessToken != "dev-token" {
+		t.Errorf("dev token = %+v, %v", token, err)
+	}
+	token, err = getOAuth2Token(context.Background(), server.Client(), auth, "prod", noBrowser)
+	if err != nil || token.AccessToken != "new" {
+		t.Errorf("prod token = %+v, %v", token, err)
+	}
+
+	// and per settings that identify the token
+	other := auth
+	other.Scope = "admin"
+	if tokenCacheKey(other) == tokenCacheKey(auth) {
+		t.Error("a different scope has the same cache key")
+	}
+	_, found := loadCachedToken("dev", other)
+	if found {
+		t.Error("token found for a different scope")
+	}
+
+	err = clearCachedToken("dev", auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, found = loadCachedToken("dev", auth)
+	if found {
+		t.Error("token found after clearing")
+	}
+}
+
+func TestOAuth2ExpiryMargin(t *testing.T) {
+	tests := []struct {
+		name  string
+		token VdatToken
+		want  bool
+	}{
+		{"empty", VdatToken{}, false},
+		{"no expiry", VdatToken{AccessToken: "a"}, true},
+		{"expired", VdatToken{AccessToken: "a", Expiry: time.Now().Add(-time.Second)}, false},
+		{"inside the margin", VdatToken{AccessToken: "a", Expiry: time.Now().Add(OAUTH2_EXPIRY_MARGIN - time.Second)}, false},
+		{"outside the margin", VdatToken{AccessToken: "a", Expiry: time.Now().Add(OAUTH2_EXPIRY_MARGIN + time.Minute)}, true},
+	}
+	for _, test := range tests {
+		if test.token.valid() != test.want {
+			t.Errorf("%s: valid() = %v, want %v", test.name, !test.want, test.want)
+		}
+	}
+
+	// a token about to expire is fetched again
+	useTempVdatDir(t)
+	server := newTokenServer(t, func(form url.Values) (int, string) {
+		return http.StatusOK, `{"access_token": "new", "expires_in": 10}`
+	})
+	auth := VdatAuth{Type: AUTH_TYPE_OAUTH2, Grant: OAUTH2_GRANT_CLIENT_CREDENTIALS, TokenUrl: server.URL}
+	for i := 0; i < 2; i++ {
+		_, err := getOAuth2Token(context.Background(), server.Client(), auth, "dev", noBrowser)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if server.requests != 2 {
+		t.Errorf("requests = %d, want a token expiring within the margin to be fetched again", server.requests)
+	}
+}
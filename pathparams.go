@@ -0,0 +1,110 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var colonPathParamPattern = regexp.MustCompile(`/:([A-Za-z_][A-Za-z0-9_\-]*)`)
+
+// matches any run of braces so that {{variables}} can be told apart
+var bracePathParamPattern = regexp.MustCompile(`\{+([A-Za-z_][A-Za-z0-9_.\-]*)\}+`)
+
+type pathParamMatch struct {
+	start int
+	end   int
+	name  string
+	colon bool
+}
+
+func splitUrlPath(urlText string) (string, string, string) {
+	rest, query, hasQuery := strings.Cut(urlText, "?")
+	if hasQuery {
+		query = "?" + query
+	}
+	pathStart := 0
+	if index := strings.Index(rest, "://"); index >= 0 {
+		pathStart = index + 3
+		slash := strings.Index(rest[pathStart:], "/")
+		if slash < 0 {
+			return rest, "", query
+		}
+		pathStart += slash
+	}
+	return rest[:pathStart], rest[pathStart:], query
+}
+
+func findPathParamMatches(path string) []pathParamMatch {
+	matches := []pathParamMatch{}
+	for _, match := range colonPathParamPattern.FindAllStringSubmatchIndex(path, -1) {
+		matches = append(matches, pathParamMatch{start: match[0], end: match[1], name: path[match[2]:match[3]], colon: true})
+	}
+	for _, match := range bracePathParamPattern.FindAllStringSubmatchIndex(path, -1) {
+		if match[2]-match[0] != 1 || match[1]-match[3] != 1 {
+			continue
+		}
+		matches = append(matches, pathParamMatch{start: match[0], end: match[1], name: path[match[2]:match[3]]})
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].start < matches[j].start })
+	return matches
+}
+
+func findPathParams(urlText string) []string {
+	_, path, _ := splitUrlPath(urlText)
+	names := []string{}
+	for _, match := range findPathParamMatches(path) {
+		if !containsString(names, match.name) {
+			names = append(names, match.name)
+		}
+	}
+	return names
+}
+
+func substitutePathParams(urlText string, pathParams string) (string, error) {
+	prefix, path, query := splitUrlPath(urlText)
+	matches := findPathParamMatches(path)
+	if len(matches) == 0 {
+		return urlText, nil
+	}
+
+	values := make(map[string]string)
+	for _, pair := range parseKeyValueLines(pathParams, "=") {
+		values[strings.TrimSpace(pair.Key)] = pair.Value
+	}
+
+	var builder strings.Builder
+	previous := 0
+	for _, match := range matches {
+		value := values[match.name]
+		if value == "" {
+			return "", errors.New(fmt.Sprint("Missing value for path parameter: ", match.name))
+		}
+		builder.WriteString(path[previous:match.start])
+		if match.colon {
+			builder.WriteString("/")
+		}
+		builder.WriteString(url.PathEscape(decodePercent(value)))
+		previous = match.end
+	}
+	builder.WriteString(path[previous:])
+	return prefix + builder.String() + query, nil
+}
+
+func syncPathParamRows(rows []KeyValueRow, names []string) []KeyValueRow {
+	synced := []KeyValueRow{}
+	for _, name := range names {
+		row := KeyValueRow{KeyValue: KeyValue{Key: name, HasValue: true}, Enabled: true}
+		for _, existing := range rows {
+			if strings.TrimSpace(existing.Key) == name {
+				row = existing
+				break
+			}
+		}
+		synced = append(synced, row)
+	}
+	return synced
+}
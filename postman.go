@@ -0,0 +1,654 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type postmanCollection struct {
+	Info     postmanInfo       `json:"info"`
+	Item     []postmanItem     `json:"item"`
+	Auth     postmanAuth       `json:"auth,omitempty"`
+	Variable []postmanKeyValue `json:"variable,omitempty"`
+	Event    []json.RawMessage `json:"event,omitempty"`
+}
+
+type postmanInfo struct {
+	Name   string `json:"name"`
+	Schema string `json:"schema"`
+}
+
+// postmanItem is a folder when Item is set and a request otherwise.
+type postmanItem struct {
+	Name                    string            `json:"name"`
+	Item                    *[]postmanItem    `json:"item,omitempty"`
+	Request                 *postmanRequest   `json:"request,omitempty"`
+	Auth                    postmanAuth       `json:"auth,omitempty"`
+	Event                   []json.RawMessage `json:"event,omitempty"`
+	Response                []json.RawMessage `json:"response,omitempty"`
+	ProtocolProfileBehavior map[string]any    `json:"protocolProfileBehavior,omitempty"`
+}
+
+type postmanRequest struct {
+	Method string            `json:"method"`
+	Header []postmanKeyValue `json:"header"`
+	Url    postmanUrl        `json:"url"`
+	Body   *postmanBody      `json:"body,omitempty"`
+	Auth   postmanAuth       `json:"auth,omitempty"`
+}
+
+// a request may also be written as just its url
+func (request *postmanRequest) UnmarshalJSON(data []byte) error {
+	var urlText string
+	if json.Unmarshal(data, &urlText) == nil {
+		*request = postmanRequest{Method: "GET", Url: postmanUrl{Raw: urlText}}
+		return nil
+	}
+	type plainRequest postmanRequest
+	var plain plainRequest
+	err := json.Unmarshal(data, &plain)
+	*request = postmanRequest(plain)
+	return err
+}
+
+type postmanUrl struct {
+	Raw      string            `json:"raw"`
+	Protocol string            `json:"protocol,omitempty"`
+	Host     []string          `json:"host,omitempty"`
+	Port     string            `json:"port,omitempty"`
+	Path     []string          `json:"path,omitempty"`
+	Query    []postmanKeyValue `json:"query,omitempty"`
+	Variable []postmanKeyValue `json:"variable,omitempty"`
+}
+
+// a url may also be written as just its raw text
+func (requestUrl *postmanUrl) UnmarshalJSON(data []byte) error {
+	var raw string
+	if json.Unmarshal(data, &raw) == nil {
+		*requestUrl = postmanUrl{Raw: raw}
+		return nil
+	}
+	var object struct {
+		Raw      string            `json:"raw"`
+		Protocol string            `json:"protocol"`
+		Host     json.RawMessage   `json:"host"`
+		Port     string            `json:"port"`
+		Path     json.RawMessage   `json:"path"`
+		Query    []postmanKeyValue `json:"query"`
+		Variable []postmanKeyValue `json:"variable"`
+	}
+	err := json.Unmarshal(data, &object)
+	if err != nil {
+		return err
+	}
+	*requestUrl = postmanUrl{Raw: object.Raw, Protocol: object.Protocol, Port: object.Port, Query: object.Query, Variable: object.Variable}
+	requestUrl.Host = postmanSegments(object.Host, ".")
+	requestUrl.Path = postmanSegments(object.Path, "/")
+	return nil
+}
+
+// postmanSegments reads a host or path written as a string or as an array
+// of strings and {"value": ...} objects.
+func postmanSegments(data json.RawMessage, separator string) []string {
+	var text string
+	if json.Unmarshal(data, &text) == nil {
+		return strings.Split(strings.TrimPrefix(text, separator), separator)
+	}
+	var segments []json.RawMessage
+	json.Unmarshal(data, &segments)
+	result := []string{}
+	for _, segment := range segments {
+		// a looseString would read an object as its "content"
+		var object struct {
+			Value looseString `json:"value"`
+		}
+		var text looseString
+		if json.Unmarshal(segment, &object) == nil {
+			text = object.Value
+		} else {
+			json.Unmarshal(segment, &text)
+		}
+		result = append(result, string(text))
+	}
+	return result
+}
+
+type postmanKeyValue struct {
+	Key         looseString `json:"key"`
+	Value       looseString `json:"value"`
+	Disabled    bool        `json:"disabled,omitempty"`
+	Description looseString `json:"description,omitempty"`
+}
+
+type postmanBody struct {
+	Mode       string              `json:"mode"`
+	Raw        looseString         `json:"raw,omitempty"`
+	Urlencoded []postmanKeyValue   `json:"urlencoded,omitempty"`
+	Formdata   []postmanFormParam  `json:"formdata,omitempty"`
+	File       *postmanFile        `json:"file,omitempty"`
+	Graphql    *postmanGraphql     `json:"graphql,omitempty"`
+	Options    *postmanBodyOptions `json:"options,omitempty"`
+	Disabled   bool                `json:"disabled,omitempty"`
+}
+
+type postmanFormParam struct {
+	Key         looseString     `json:"key"`
+	Value       looseString     `json:"value,omitempty"`
+	Src         json.RawMessage `json:"src,omitempty"`
+	Type        string          `json:"type"`
+	ContentType string          `json:"contentType,omitempty"`
+	Disabled    bool            `json:"disabled,omitempty"`
+}
+
+type postmanFile struct {
+	Src looseString `json:"src"`
+}
+
+type postmanGraphql struct {
+	Query     string      `json:"query"`
+	Variables looseString `json:"variables"`
+}
+
+type postmanBodyOptions struct {
+	Raw struct {
+		Language string `json:"language"`
+	} `json:"raw"`
+}
+
+// postmanAuth keeps the auth object as written, {"type": "basic",
+// "basic": [{"key": "username", "value": "..."}]}, the v2.0 form with an
+// object in place of the array is read as well.
+type postmanAuth map[string]json.RawMessage
+
+func (auth postmanAuth) authType() string {
+	var authType string
+	json.Unmarshal(auth["type"], &authType)
+	return authType
+}
+
+func (auth postmanAuth) attributes() map[string]string {
+	attributes := make(map[string]string)
+	data := auth[auth.authType()]
+	var pairs []postmanKeyValue
+	if json.Unmarshal(data, &pairs) == nil {
+		for _, pair := range pairs {
+			attributes[string(pair.Key)] = string(pair.Value)
+		}
+		return attributes
+	}
+	var object map[string]looseString
+	json.Unmarshal(data, &object)
+	for key, value := range object {
+		attributes[key] = string(value)
+	}
+	return attributes
+}
+
+func newPostmanAuth(authType string, attributes ...string) postmanAuth {
+	pairs := []map[string]string{}
+	for index := 0; index+1 < len(attributes); index += 2 {
+		if attributes[index+1] != "" {
+			pairs = append(pairs, map[string]string{"key": attributes[index], "value": attributes[index+1], "type": "string"})
+		}
+	}
+	typeJson, _ := json.Marshal(authType)
+	pairsJson, _ := json.Marshal(pairs)
+	return postmanAuth{"type": typeJson, authType: pairsJson}
+}
+
+var postmanRawLanguages = map[string]string{
+	"json": RAW_LANGUAGE_JSON,
+	"xml":  RAW_LANGUAGE_XML,
+	"html": RAW_LANGUAGE_HTML,
+	"text": RAW_LANGUAGE_TEXT,
+}
+
+var postmanOAuth2Grants = map[string]string{
+	"client_credentials":           OAUTH2_GRANT_CLIENT_CREDENTIALS,
+	"password_credentials":         OAUTH2_GRANT_PASSWORD,
+	"authorization_code_with_pkce": OAUTH2_GRANT_AUTHORIZATION_CODE,
+	"authorization_code":           OAUTH2_GRANT_AUTHORIZATION_CODE,
+}
+
+func postmanRows(pairs []postmanKeyValue) []KeyValueRow {
+	rows := []KeyValueRow{}
+	for _, pair := range pairs {
+		rows = append(rows, KeyValueRow{
+			KeyValue:    KeyValue{Key: string(pair.Key), Value: string(pair.Value), HasValue: true},
+			Description: strings.Join(strings.Fields(string(pair.Description)), " "),
+			Enabled:     !pair.Disabled,
+		})
+	}
+	return rows
+}
+
+func postmanAuthToVdat(auth postmanAuth, collection *collectionImport, where string) VdatAuth {
+	attributes := auth.attributes()
+	switch auth.authType() {
+	case "", "noauth":
+		return VdatAuth{Type: AUTH_TYPE_NONE}
+	case "basic":
+		return VdatAuth{Type: AUTH_TYPE_BASIC, Username: attributes["username"], Password: attributes["password"]}
+	case "digest":
+		return VdatAuth{Type: AUTH_TYPE_DIGEST, Username: attributes["username"], Password: attributes["password"]}
+	case "bearer":
+		return VdatAuth{Type: AUTH_TYPE_BEARER, Token: attributes["token"]}
+	case "apikey":
+		in := API_KEY_IN_HEADER
+		if attributes["in"] == "query" {
+			in = API_KEY_IN_QUERY
+		}
+		return VdatAuth{Type: AUTH_TYPE_API_KEY, Key: attributes["key"], Value: attributes["value"], In: in}
+	case "awsv4":
+		return VdatAuth{Type: AUTH_TYPE_AWS_SIGV4, AccessKey: attributes["accessKey"], SecretKey: attributes["secretKey"], Region: attributes["region"], Service: attributes["service"], SessionToken: attributes["sessionToken"]}
+	case "oauth2":
+		grant, found := postmanOAuth2Grants[attributes["grant_type"]]
+		if !found {
+			grant = OAUTH2_GRANT_AUTHORIZATION_CODE
+			if attributes["grant_type"] != "" {
+				collection.warn(where, "OAuth 2.0 grant ", attributes["grant_type"], " is not supported, using ", grant)
+			}
+		}
+		return VdatAuth{
+			Type:         AUTH_TYPE_OAUTH2,
+			Grant:        grant,
+			TokenUrl:     attributes["accessTokenUrl"],
+			AuthUrl:      attributes["authUrl"],
+			ClientId:     attributes["clientId"],
+			ClientSecret: attributes["clientSecret"],
+			Scope:        attributes["scope"],
+			Username:     attributes["username"],
+			Password:     attributes["password"],
+		}
+	}
+	collection.warn(where, "auth type ", auth.authType(), " is not supported")
+	return VdatAuth{Type: AUTH_TYPE_NONE}
+}
+
+func postmanRequestToVdat(item postmanItem, inheritedAuth postmanAuth, collection *collectionImport, where string) VdatRequest {
+	request := item.Request
+	vdatRequest := VdatRequest{
+		Title:      item.Name,
+		RestMethod: collection.method(where, request.Method),
+		SslEnabled: true,
+		BodyType:   BODY_TYPE_NONE,
+	}
+	if strictSsl, found := item.ProtocolProfileBehavior["strictSSL"].(bool); found && !strictSsl {
+		vdatRequest.SslEnabled = false
+	}
+	if len(item.Event) != 0 {
+		collection.warn(where, "scripts are not converted")
+	}
+	if len(item.Response) != 0 {
+		collection.warn(where, "saved responses are not converted")
+	}
+
+	// url, the query and path variables are kept as rows
+	requestUrl := request.Url
+	raw := requestUrl.Raw
+	if raw == "" {
+		if requestUrl.Protocol != "" {
+			raw = requestUrl.Protocol + "://"
+		}
+		raw += strings.Join(requestUrl.Host, ".")
+		if requestUrl.Port != "" {
+			raw += ":" + requestUrl.Port
+		}
+		if len(requestUrl.Path) != 0 {
+			raw += "/" + strings.Join(requestUrl.Path, "/")
+		}
+	}
+	base, query := splitUrlQuery(raw)
+	vdatRequest.Url = base
+	if requestUrl.Query != nil {
+		vdatRequest.Params = formatKeyValueRows(readableQueryRows(postmanRows(requestUrl.Query)), "=")
+	} else {
+		vdatRequest.Params = formatKeyValueLines(readableQueryPairs(query), "=")
+	}
+	vdatRequest.PathParams = formatKeyValueRows(postmanRows(requestUrl.Variable), "=")
+
+	headers := postmanRows(request.Header)
+
+	auth := inheritedAuth
+	if request.Auth != nil {
+		auth = request.Auth
+	}
+	vdatRequest.Auth = postmanAuthToVdat(auth, collection, where)
+
+	body := request.Body
+	if body == nil || body.Disabled {
+		body = &postmanBody{}
+	}
+	switch body.Mode {
+	case "", "none":
+	case "raw":
+		vdatRequest.BodyType = BODY_TYPE_RAW
+		vdatRequest.BodyContent = string(body.Raw)
+		language := "text"
+		if body.Options != nil && body.Options.Raw.Language != "" {
+			language = body.Options.Raw.Language
+		}
+		vdatRequest.RawLanguage = postmanRawLanguages[language]
+		if vdatRequest.RawLanguage == "" && language == "javascript" && !hasHeader(formatKeyValueRows(headers, "\t"), "Content-Type") {
+			headers = append(headers, KeyValueRow{KeyValue: KeyValue{Key: "Content-Type", Value: "application/javascript", HasValue: true}, Enabled: true})
+		}
+	case "urlencoded":
+		vdatRequest.BodyType = BODY_TYPE_FORM
+		vdatRequest.BodyContent = formatKeyValueRows(literalRows(postmanRows(body.Urlencoded)), "=")
+	case "formdata":
+		vdatRequest.BodyType = BODY_TYPE_MULTIPART
+		lines := []string{}
+		for _, param := range body.Formdata {
+			parts := []VdatMultipartPart{}
+			if param.Type == "file" {
+				var sources []string
+				var source string
+				if json.Unmarshal(param.Src, &source) == nil {
+					sources = []string{source}
+				} else {
+					json.Unmarshal(param.Src, &sources)
+				}
+				for _, source := range sources {
+					if source != "" {
+						parts = append(parts, VdatMultipartPart{Name: string(param.Key), Value: source, IsFile: true, ContentType: param.ContentType})
+					}
+				}
+				if len(parts) == 0 {
+					collection.warn(where, "form file ", param.Key, " has no file selected")
+				}
+			} else {
+				value := string(param.Value)
+				if strings.HasPrefix(value, "@") || strings.HasPrefix(value, "<") {
+					collection.warn(where, "form value ", param.Key, " starts with ", value[:1], " and is read as a file")
+				}
+				parts = append(parts, VdatMultipartPart{Name: string(param.Key), Value: value, ContentType: param.ContentType})
+			}
+			for _, part := range parts {
+				line := part.String()
+				if param.Disabled {
+					line = "#" + line
+				}
+				lines = append(lines, line)
+			}
+		}
+		vdatRequest.BodyContent = strings.Join(lines, "\n")
+	case "file":
+		vdatRequest.BodyType = BODY_TYPE_FILE
+		if body.File != nil {
+			vdatRequest.BodyFile = string(body.File.Src)
+		}
+		if vdatRequest.BodyFile == "" {
+			collection.warn(where, "body file has no file selected")
+		}
+	case "graphql":
+		// sent the way Postman sends it, as a JSON object
+		graphql := map[string]any{"query": ""}
+		if body.Graphql != nil {
+			graphql["query"] = body.Graphql.Query
+			variables := strings.TrimSpace(string(body.Graphql.Variables))
+			if variables != "" && json.Valid([]byte(variables)) {
+				graphql["variables"] = json.RawMessage(variables)
+			} else if variables != "" {
+				collection.warn(where, "GraphQL variables are not valid JSON and were left out")
+			}
+		}
+		content, _ := json.MarshalIndent(graphql, "", "  ")
+		vdatRequest.BodyType = BODY_TYPE_RAW
+		vdatRequest.RawLanguage = RAW_LANGUAGE_JSON
+		vdatRequest.BodyContent = string(content)
+	default:
+		collection.warn(where, "body mode ", body.Mode, " is not supported")
+	}
+	vdatRequest.Headers = formatKeyValueRows(withoutImpliedContentType(headers, vdatRequest), "\t")
+	return vdatRequest
+}
+
+func importPostmanItems(items []postmanItem, folder string, inheritedAuth postmanAuth, collection *collectionImport, where string) error {
+	for _, item := range items {
+		itemWhere := where + "/" + item.Name
+		if item.Item != nil {
+			subfolder, err := collection.addFolder(folder, item.Name)
+			if err != nil {
+				return err
+			}
+			auth := inheritedAuth
+			if item.Auth != nil {
+				auth = item.Auth
+			}
+			if len(item.Event) != 0 {
+				collection.warn(itemWhere, "folder scripts are not converted")
+			}
+			err = importPostmanItems(*item.Item, subfolder, auth, collection, itemWhere)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if item.Request == nil {
+			collection.warn(itemWhere, "has no request")
+			continue
+		}
+		err := collection.addRequest(folder, postmanRequestToVdat(item, inheritedAuth, collection, itemWhere))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// importPostmanCollection reads a Postman v2.0 or v2.1 collection into a new
+// folder under parent. Collection variables go to an environment named
+// after the collection.
+func importPostmanCollection(content []byte, parent string, collection *collectionImport) error {
+	var postman postmanCollection
+	err := json.Unmarshal(content, &postman)
+	if err != nil {
+		return errors.New(fmt.Sprint("Error reading Postman collection: ", err))
+	}
+	if !strings.Contains(postman.Info.Schema, "/collection/v2") {
+		return errors.New(fmt.Sprint("Unsupported Postman collection schema: ", postman.Info.Schema))
+	}
+
+	folder, err := collection.addCollection(parent, postman.Info.Name)
+	if err != nil {
+		return err
+	}
+	if len(postman.Event) != 0 {
+		collection.warn(postman.Info.Name, "collection scripts are not converted")
+	}
+	err = importPostmanItems(postman.Item, folder, postman.Auth, collection, postman.Info.Name)
+	if err != nil {
+		return err
+	}
+	return collection.addEnvironment(postman.Info.Name, postmanRows(postman.Variable))
+}
+
+func vdatAuthToPostman(auth VdatAuth, where string, warnings *[]string) postmanAuth {
+	switch auth.Type {
+	case AUTH_TYPE_BASIC:
+		return newPostmanAuth("basic", "username", auth.Username, "password", auth.Password)
+	case AUTH_TYPE_DIGEST:
+		return newPostmanAuth("digest", "username", auth.Username, "password", auth.Password)
+	case AUTH_TYPE_BEARER:
+		return newPostmanAuth("bearer", "token", auth.Token)
+	case AUTH_TYPE_API_KEY:
+		in := "header"
+		if auth.In == API_KEY_IN_QUERY {
+			in = "query"
+		}
+		return newPostmanAuth("apikey", "key", auth.Key, "value", auth.Value, "in", in)
+	case AUTH_TYPE_AWS_SIGV4:
+		return newPostmanAuth("awsv4", "accessKey", auth.AccessKey, "secretKey", auth.SecretKey, "region", auth.Region, "service", auth.Service, "sessionToken", auth.SessionToken)
+	case AUTH_TYPE_OAUTH2:
+		grant := ""
+		for postmanGrant, vdatGrant := range postmanOAuth2Grants {
+			if vdatGrant == auth.Grant && postmanGrant != "authorization_code" {
+				grant = postmanGrant
+			}
+		}
+		if grant == "" {
+			*warnings = append(*warnings, fmt.Sprint(where, ": OAuth 2.0 grant ", auth.Grant, " has no Postman equivalent"))
+		}
+		return newPostmanAuth("oauth2", "grant_type", grant, "accessTokenUrl", auth.TokenUrl, "authUrl", auth.AuthUrl, "clientId", auth.ClientId, "clientSecret", auth.ClientSecret, "scope", auth.Scope, "username", auth.Username, "password", auth.Password, "addTokenTo", "header")
+	case AUTH_TYPE_HMAC:
+		*warnings = append(*warnings, fmt.Sprint(where, ": HMAC auth has no Postman equivalent"))
+	}
+	return nil
+}
+
+func vdatRowsToPostman(rows []KeyValueRow) []postmanKeyValue {
+	pairs := []postmanKeyValue{}
+	for _, row := range rows {
+		pairs = append(pairs, postmanKeyValue{
+			Key:         looseString(strings.TrimSpace(row.Key)),
+			Value:       looseString(row.Value),
+			Disabled:    !row.Enabled,
+			Description: looseString(row.Description),
+		})
+	}
+	return pairs
+}
+
+// splitPostmanUrl splits a url that may hold {{variables}} in any part, so
+// url.Parse cannot be used.
+func splitPostmanUrl(base string) postmanUrl {
+	requestUrl := postmanUrl{}
+	rest := base
+	if protocol, remainder, found := strings.Cut(rest, "://"); found {
+		requestUrl.Protocol = protocol
+		rest = remainder
+	}
+	host, path, _ := strings.Cut(rest, "/")
+	if index := strings.LastIndex(host, ":"); index >= 0 && !strings.Contains(host[index:], "}") && !strings.HasSuffix(host, "]") {
+		host, requestUrl.Port = host[:index], host[index+1:]
+	}
+	requestUrl.Host = strings.Split(host, ".")
+	if path != "" {
+		requestUrl.Path = strings.Split(path, "/")
+	}
+	return requestUrl
+}
+
+func vdatRequestToPostman(vdatRequest VdatRequest, name string, warnings *[]string) postmanItem {
+	where := name
+	request := &postmanRequest{Method: vdatRequest.RestMethod, Header: vdatRowsToPostman(parseKeyValueRows(vdatRequest.Headers, "\t"))}
+	paramRows := parseKeyValueRows(vdatRequest.Params, "=")
+	request.Url = splitPostmanUrl(vdatRequest.Url)
+	request.Url.Raw = composeUrl(vdatRequest.Url, paramRows)
+	request.Url.Query = vdatRowsToPostman(paramRows)
+	request.Url.Variable = vdatRowsToPostman(parseKeyValueRows(vdatRequest.PathParams, "="))
+	request.Auth = vdatAuthToPostman(vdatRequest.Auth, where, warnings)
+
+	switch vdatRequest.BodyType {
+	case BODY_TYPE_RAW:
+		request.Body = &postmanBody{Mode: "raw", Raw: looseString(vdatRequest.BodyContent), Options: &postmanBodyOptions{}}
+		request.Body.Options.Raw.Language = "text"
+		for postmanLanguage, language := range postmanRawLanguages {
+			if language == vdatRequest.RawLanguage {
+				request.Body.Options.Raw.Language = postmanLanguage
+			}
+		}
+	case BODY_TYPE_FORM:
+		// Postman keeps form values as sent, not percent-encoded
+		rows := parseKeyValueRows(vdatRequest.BodyContent, "=")
+		for index, row := range rows {
+			rows[index].Key = decodePercent(row.Key)
+			rows[index].Value = decodePercent(row.Value)
+		}
+		request.Body = &postmanBody{Mode: "urlencoded", Urlencoded: vdatRowsToPostman(rows)}
+	case BODY_TYPE_MULTIPART:
+		request.Body = &postmanBody{Mode: "formdata", Formdata: []postmanFormParam{}}
+		for _, line := range strings.Split(vdatRequest.BodyContent, "\n") {
+			disabled := strings.HasPrefix(line, "#")
+			line = strings.TrimPrefix(line, "#")
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			part, err := parseMultipartPart(line)
+			if err != nil {
+				*warnings = append(*warnings, fmt.Sprint(where, ": ", err))
+				continue
+			}
+			param := postmanFormParam{Key: looseString(part.Name), Type: "text", ContentType: part.ContentType, Disabled: disabled}
+			if part.IsFile {
+				param.Type = "file"
+				param.Src, _ = json.Marshal(part.Value)
+			} else {
+				if part.FromFile {
+					*warnings = append(*warnings, fmt.Sprint(where, ": form value ", part.Name, " read from a file is exported as its file name"))
+				}
+				param.Value = looseString(part.Value)
+			}
+			request.Body.Formdata = append(request.Body.Formdata, param)
+		}
+	case BODY_TYPE_FILE:
+		request.Body = &postmanBody{Mode: "file", File: &postmanFile{Src: looseString(vdatRequest.BodyFile)}}
+	}
+
+	item := postmanItem{Name: name, Request: request}
+	if !vdatRequest.SslEnabled {
+		item.ProtocolProfileBehavior = map[string]any{"strictSSL": false}
+	}
+	if vdatRequest.Tls != (VdatTls{}) {
+		*warnings = append(*warnings, fmt.Sprint(where, ": TLS settings are not exported"))
+	}
+	if vdatRequest.Proxy != (VdatProxy{}) {
+		*warnings = append(*warnings, fmt.Sprint(where, ": proxy settings are not exported"))
+	}
+	return item
+}
+
+func exportPostmanItems(folder string, warnings *[]string) ([]postmanItem, error) {
+	files, err := os.ReadDir(folder)
+	if err != nil {
+		return nil, err
+	}
+	items := []postmanItem{}
+	for _, file := range files {
+		if strings.HasPrefix(file.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(folder, file.Name())
+		if file.IsDir() {
+			children, err := exportPostmanItems(path, warnings)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, postmanItem{Name: file.Name(), Item: &children})
+			continue
+		}
+		vdatRequest, err := loadVdatRequest(path)
+		if err != nil {
+			*warnings = append(*warnings, fmt.Sprint(file.Name(), ": not a request, skipped"))
+			continue
+		}
+		name := vdatRequest.Title
+		if name == "" {
+			name = file.Name()
+		}
+		items = append(items, vdatRequestToPostman(vdatRequest, name, warnings))
+	}
+	return items, nil
+}
+
+// exportPostmanCollection writes a folder as a Postman v2.1 collection, with
+// the environment's variables as collection variables. The export button
+// only passes an environment the user agreed to share.
+func exportPostmanCollection(folder string, environment VdatEnvironment) ([]byte, []string, error) {
+	warnings := []string{}
+	items, err := exportPostmanItems(folder, &warnings)
+	if err != nil {
+		return nil, nil, err
+	}
+	postman := postmanCollection{
+		Info: postmanInfo{Name: filepath.Base(folder), Schema: POSTMAN_SCHEMA},
+		Item: items,
+	}
+	postman.Variable = vdatRowsToPostman(parseKeyValueRows(environment.Variables, "="))
+	content, err := json.MarshalIndent(postman, "", "\t")
+	return content, warnings, err
+}
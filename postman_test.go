@@ -0,0 +1,208 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPostmanRoundTrip(t *testing.T) {
+	useTempVdatDir(t)
+	vdatDir, err := getVdatDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := filepath.Join(vdatDir, "exported", "Shop")
+	err = os.MkdirAll(filepath.Join(source, "orders"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	requests := map[string]VdatRequest{
+		"GET - List": {
+			Title:       "List",
+			RestMethod:  "GET",
+			Url:         "https://{{host}}:8443/items/:id",
+			PathParams:  "## the item to list\nid=7",
+			Params:      "q=a b\n#page=2\n## sort order\nsort=name&id\nempty=",
+			Headers:     "Accept\tapplication/json\n#X-Debug\t1",
+			BodyType:    BODY_TYPE_NONE,
+			SslEnabled:  true,
+			Auth:        VdatAuth{Type: AUTH_TYPE_BEARER, Token: "{{token}}"},
+			RawLanguage: "",
+		},
+		"PUT - Raw": {
+			Title:       "Raw",
+			RestMethod:  "PUT",
+			Url:         "http://localhost/raw",
+			BodyType:    BODY_TYPE_RAW,
+			BodyContent: `{"a": 1}`,
+			RawLanguage: RAW_LANGUAGE_JSON,
+			Auth:        VdatAuth{Type: AUTH_TYPE_API_KEY, Key: "api_key", Value: "k", In: API_KEY_IN_QUERY},
+		},
+		filepath.Join("orders", "POST - Create"): {
+			Title:       "Create",
+			RestMethod:  "POST",
+			Url:         "https://example.com/orders",
+			BodyType:    BODY_TYPE_FORM,
+			BodyContent: "a=x&y\nb=100%25\n## turned off\n#c=off",
+			SslEnabled:  true,
+			Auth:        VdatAuth{Type: AUTH_TYPE_BASIC, Username: "user", Password: "p&ss"},
+		},
+		filepath.Join("orders", "POST - Upload"): {
+			Title:       "Upload",
+			RestMethod:  "POST",
+			Url:         "https://example.com/upload",
+			BodyType:    BODY_TYPE_MULTIPART,
+			BodyContent: "title=hello\npic=@/tmp/pic.png;type=image/png\n#old=@/tmp/old.png",
+			SslEnabled:  true,
+			Auth:        VdatAuth{Type: AUTH_TYPE_NONE},
+		},
+	}
+	for name, vdatRequest := range requests {
+		err = saveVdatRequest(filepath.Join(source, name), vdatRequest)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	content, warnings, err := exportPostmanCollection(source, VdatEnvironment{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("export warnings = %q", warnings)
+	}
+	exported := filepath.Join(t.TempDir(), "shop.postman_collection.json")
+	err = os.WriteFile(exported, content, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	imported := filepath.Join(vdatDir, "imported")
+	err = os.MkdirAll(imported, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	collection, err := importCollection(exported, imported)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(collection.warnings) != 0 || collection.requests != len(requests) {
+		t.Errorf("imported %d requests, warnings = %q", collection.requests, collection.warnings)
+	}
+
+	for name, want := range requests {
+		got, err := loadVdatRequest(filepath.Join(imported, "Shop", name))
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		got.Path = ""
+		if want.Auth.Type == "" {
+			want.Auth.Type = AUTH_TYPE_NONE
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s:\n got %+v\nwant %+v", name, got, want)
+		}
+	}
+
+	// form values are exported as sent and kept literal on import
+	form, err := loadVdatRequest(filepath.Join(imported, "Shop", "orders", "POST - Create"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sent := send(t, form, time.Now())
+	if sent.body != "a=x%26y&b=100%25" {
+		t.Errorf("form body = %q", sent.body)
+	}
+}
+
+func TestImportPostmanForms(t *testing.T) {
+	useTempVdatDir(t)
+	vdatDir, err := getVdatDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := `{
+	"info": {"name": "Forms", "schema": "https://schema.getpostman.com/json/collection/v2.0.0/collection.json"},
+	"auth": {"type": "bearer", "bearer": [{"key": "token", "value": "collection-token"}]},
+	"item": [
+		{"name": "String request", "request": "https://example.com/a?x=1+2"},
+		{"name": "String url", "request": {"method": "POST", "url": "https://example.com/b#top"}},
+		{"name": "Object url", "request": {"method": "GET", "url": {
+			"protocol": "https", "host": "api.example.com", "port": "8080",
+			"path": [{"value": "v1"}, {"value": ":id"}],
+			"query": [{"key": "q", "value": "a+b", "description": {"content": "the query"}}],
+			"variable": [{"key": "id", "value": 3}]
+		}}},
+		{"name": "Basic folder", "auth": {"type": "basic", "basic": {"username": "folder-user", "password": "pw"}}, "item": [
+			{"name": "Inherited", "request": {"method": "GET", "url": "https://example.com/c"}},
+			{"name": "No auth", "request": {"method": "GET", "url": "https://example.com/d", "auth": {"type": "noauth"}}}
+		]}
+	]
+}`
+	path := filepath.Join(t.TempDir(), "forms.json")
+	err = os.WriteFile(path, []byte(content), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	collection, err := importCollection(path, vdatDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(collection.warnings) != 0 {
+		t.Errorf("warnings = %q", collection.warnings)
+	}
+
+	bearer := VdatAuth{Type: AUTH_TYPE_BEARER, Token: "collection-token"}
+	tests := []struct {
+		file       string
+		url        string
+		params     string
+		pathParams string
+		auth       VdatAuth
+	}{
+		{"GET - String request", "https://example.com/a", "x=1 2\n", "", bearer},
+		{"POST - String url", "https://example.com/b", "", "", bearer},
+		{"GET - Object url", "https://api.example.com:8080/v1/:id", "## the query\nq=a b", "id=3", bearer},
+		{filepath.Join("Basic folder", "GET - Inherited"), "https://example.com/c", "", "", VdatAuth{Type: AUTH_TYPE_BASIC, Username: "folder-user", Password: "pw"}},
+		{filepath.Join("Basic folder", "GET - No auth"), "https://example.com/d", "", "", VdatAuth{Type: AUTH_TYPE_NONE}},
+	}
+	for _, test := range tests {
+		got, err := loadVdatRequest(filepath.Join(vdatDir, "Forms", test.file))
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if got.Url != test.url || got.Params != test.params || got.PathParams != test.pathParams || got.Auth != test.auth {
+			t.Errorf("%s: got url %q, params %q, path params %q, auth %+v", test.file, got.Url, got.Params, got.PathParams, got.Auth)
+		}
+	}
+}
+
+func TestExportPostmanVariables(t *testing.T) {
+	useTempVdatDir(t)
+	folder := t.TempDir()
+	for _, test := range []struct {
+		environment VdatEnvironment
+		want        int
+	}{
+		{VdatEnvironment{}, 0},
+		{VdatEnvironment{Name: "dev", Variables: "host=example.com\ntoken=s3cret"}, 2},
+	} {
+		content, _, err := exportPostmanCollection(folder, test.environment)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var postman postmanCollection
+		err = json.Unmarshal(content, &postman)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(postman.Variable) != test.want {
+			t.Errorf("%q: variables = %+v", test.environment.Name, postman.Variable)
+		}
+	}
+}
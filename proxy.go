@@ -0,0 +1,155 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"fyne.io/fyne/v2/widget"
+)
+
+type VdatProxy struct {
+	Url      string `json:"Url"`
+	Username string `json:"Username"`
+	Password string `json:"Password"`
+	NoProxy  string `json:"NoProxy"`
+}
+
+// override keeps the proxy url and its credentials together.
+func (proxy VdatProxy) override(overrides VdatProxy) VdatProxy {
+	if overrides.Url != "" {
+		proxy.Url = overrides.Url
+		proxy.Username = overrides.Username
+		proxy.Password = overrides.Password
+	}
+	if overrides.NoProxy != "" {
+		proxy.NoProxy = overrides.NoProxy
+	}
+	return proxy
+}
+
+func parseProxyUrl(proxy VdatProxy) (*url.URL, error) {
+	if proxy.Url == "" {
+		return nil, nil
+	}
+	text := proxy.Url
+	// like curl, a bare host:port is an http proxy
+	if !strings.Contains(text, "://") {
+		text = "http://" + text
+	}
+	proxyUrl, err := url.Parse(text)
+	if err != nil || proxyUrl.Host == "" {
+		return nil, errors.New(fmt.Sprint("Invalid proxy url: ", proxy.Url))
+	}
+	switch proxyUrl.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, errors.New(fmt.Sprint("Unsupported proxy scheme: ", proxyUrl.Scheme))
+	}
+	if proxy.Username != "" {
+		proxyUrl.User = url.UserPassword(proxy.Username, proxy.Password)
+	}
+	return proxyUrl, nil
+}
+
+func (proxy VdatProxy) validate() error {
+	_, err := parseProxyUrl(proxy)
+	return err
+}
+
+// noProxyMatch follows curl's no-proxy list: "*" matches every host, a name
+// matches itself and its subdomains, an IP or CIDR range matches addresses.
+func noProxyMatch(requestUrl *url.URL, noProxy string) bool {
+	host := strings.ToLower(requestUrl.Hostname())
+	port := requestUrl.Port()
+	ip := net.ParseIP(host)
+	for _, entry := range strings.Split(noProxy, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if entry == "*" {
+			return true
+		}
+		if ip != nil {
+			if _, network, err := net.ParseCIDR(entry); err == nil {
+				if network.Contains(ip) {
+					return true
+				}
+				continue
+			}
+		}
+		entryHost, entryPort, err := net.SplitHostPort(entry)
+		if err != nil {
+			entryHost, entryPort = entry, ""
+		}
+		entryHost = strings.Trim(entryHost, "[]")
+		if entryPort != "" && entryPort != port {
+			continue
+		}
+		entryHost = strings.TrimPrefix(entryHost, "*")
+		entryHost = strings.TrimPrefix(entryHost, ".")
+		if host == entryHost || strings.HasSuffix(host, "."+entryHost) {
+			return true
+		}
+	}
+	return false
+}
+
+// proxyFunc falls back to the proxy environment variables when no proxy url
+// is set, so vdat behaves as before for anyone relying on them.
+func (proxy VdatProxy) proxyFunc() (func(*http.Request) (*url.URL, error), error) {
+	proxyUrl, err := parseProxyUrl(proxy)
+	if err != nil {
+		return nil, err
+	}
+	return func(req *http.Request) (*url.URL, error) {
+		if noProxyMatch(req.URL, proxy.NoProxy) {
+			return nil, nil
+		}
+		if proxyUrl == nil {
+			return http.ProxyFromEnvironment(req)
+		}
+		return proxyUrl, nil
+	}, nil
+}
+
+func newProxyEntries(placeholders VdatProxy) (*widget.Form, func() VdatProxy, func(VdatProxy)) {
+	proxyUrl := widget.NewEntry()
+	proxyUrl.SetPlaceHolder(placeholders.Url)
+	if placeholders.Url == "" {
+		proxyUrl.SetPlaceHolder(PROXY_URL_PLACEHOLDER)
+	}
+	username := widget.NewEntry()
+	username.SetPlaceHolder(placeholders.Username)
+	password := widget.NewPasswordEntry()
+	noProxy := widget.NewEntry()
+	noProxy.SetPlaceHolder(placeholders.NoProxy)
+	if placeholders.NoProxy == "" {
+		noProxy.SetPlaceHolder(NO_PROXY_PLACEHOLDER)
+	}
+
+	form := widget.NewForm(
+		widget.NewFormItem(PROXY_URL_TEXT, proxyUrl),
+		widget.NewFormItem(PROXY_USERNAME_TEXT, username),
+		widget.NewFormItem(PROXY_PASSWORD_TEXT, password),
+		widget.NewFormItem(NO_PROXY_TEXT, noProxy))
+	getProxy := func() VdatProxy {
+		return VdatProxy{
+			Url:      proxyUrl.Text,
+			Username: username.Text,
+			Password: password.Text,
+			NoProxy:  noProxy.Text,
+		}
+	}
+	setProxy := func(proxy VdatProxy) {
+		proxyUrl.SetText(proxy.Url)
+		username.SetText(proxy.Username)
+		password.SetText(proxy.Password)
+		noProxy.SetText(proxy.NoProxy)
+	}
+	return form, getProxy, setProxy
+}
@@ -0,0 +1,405 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+type VdatResponse struct {
+	Response *http.Response
+	Body     []byte
+	Elapsed  time.Duration
+	Timing   VdatTiming
+}
+
+func loadVdatRequest(filename string) (VdatRequest, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return VdatRequest{}, err
+	}
+	defer file.Close()
+
+	// Create an instance of the struct to load data into
+	vdatRequest := VdatRequest{}
+
+	// Create a JSON decoder and decode the file content into the struct
+	decoder := json.NewDecoder(file)
+	err = decoder.Decode(&vdatRequest)
+	vdatRequest.Path = filename
+	return vdatRequest, err
+}
+
+func saveVdatRequest(filename string, vdatRequest VdatRequest) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Serialize the struct to JSON
+	encoder := json.NewEncoder(file)
+	return encoder.Encode(vdatRequest)
+}
+
+func buildHttpRequest(vdatRequest VdatRequest) (*http.Request, error) {
+	// prepare url with path params and params
+	urlText, err := substitutePathParams(vdatRequest.Url, vdatRequest.PathParams)
+	if err != nil {
+		return nil, err
+	}
+	urlText, err = buildRequestUrl(urlText, vdatRequest.Params)
+	if err != nil {
+		return nil, err
+	}
+	urlText, err = addAuthQuery(urlText, vdatRequest.Auth)
+	if err != nil {
+		return nil, err
+	}
+
+	// prepare body
+	var body io.Reader
+	var contentType string
+	var bodyFile *os.File
+	var bodyFileSize int64
+	if vdatRequest.BodyType == BODY_TYPE_NONE {
+		body = strings.NewReader(string(""))
+	} else if vdatRequest.BodyType == BODY_TYPE_RAW {
+		body = strings.NewReader(vdatRequest.BodyContent)
+		contentType = rawContentType(vdatRequest.RawLanguage)
+	} else if vdatRequest.BodyType == BODY_TYPE_FORM {
+		bodyText, err := encodeKeyValues(parseKeyValueLines(vdatRequest.BodyContent, "="), "body")
+		if err != nil {
+			return nil, err
+		}
+		body = strings.NewReader(bodyText)
+		contentType = CONTENT_TYPE_FORM
+	} else if vdatRequest.BodyType == BODY_TYPE_MULTIPART {
+		body, contentType, err = buildMultipartBody(vdatRequest.BodyContent, vdatRequest.Path)
+		if err != nil {
+			return nil, err
+		}
+	} else if vdatRequest.BodyType == BODY_TYPE_FILE {
+		bodyFile, bodyFileSize, contentType, err = openBodyFile(vdatRequest.BodyFile, vdatRequest.Path)
+		if err != nil {
+			return nil, err
+		}
+		body = bodyFile
+		if bodyFileSize == 0 {
+			bodyFile.Close()
+			bodyFile = nil
+			body = http.NoBody
+		}
+	}
+
+	// create request
+	req, err := http.NewRequest(vdatRequest.RestMethod, urlText, body)
+	if err != nil {
+		if bodyFile != nil {
+			bodyFile.Close()
+		}
+		return nil, err
+	}
+
+	// stream file bodies with a known length
+	if bodyFile != nil {
+		req.ContentLength = bodyFileSize
+		req.GetBody = func() (io.ReadCloser, error) {
+			return os.Open(bodyFile.Name())
+		}
+	}
+
+	// parse form if applicable
+	if vdatRequest.BodyType == BODY_TYPE_FORM {
+		err = req.ParseForm()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// set headers, repeated lines become repeated values in the order written
+	if contentType != "" && !hasHeader(vdatRequest.Headers, "Content-Type") {
+		req.Header.Set("Content-Type", contentType)
+	}
+	for _, pair := range parseKeyValueLines(vdatRequest.Headers, "\t") {
+		if pair.HasValue {
+			key := strings.TrimSpace(pair.Key)
+			value := strings.TrimSpace(pair.Value)
+			if validHeaderName(key) && validHeaderValue(value) {
+				req.Header.Add(key, value)
+			} else {
+				if req.Body != nil {
+					req.Body.Close()
+				}
+				return nil, errors.New(fmt.Sprint("Error with header entry: ", key, "=", value))
+			}
+		}
+	}
+
+	// set auth
+	err = applyAuth(req, vdatRequest)
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+
+	return req, nil
+}
+
+func impliedContentType(vdatRequest VdatRequest) string {
+	switch vdatRequest.BodyType {
+	case BODY_TYPE_FORM:
+		return CONTENT_TYPE_FORM
+	case BODY_TYPE_RAW:
+		return rawContentType(vdatRequest.RawLanguage)
+	case BODY_TYPE_MULTIPART:
+		return CONTENT_TYPE_MULTIPART
+	case BODY_TYPE_FILE:
+		return bodyFileContentType(vdatRequest.BodyFile)
+	}
+	return ""
+}
+
+func hasHeader(headers string, name string) bool {
+	for _, pair := range parseKeyValueLines(headers, "\t") {
+		if pair.HasValue && strings.EqualFold(strings.TrimSpace(pair.Key), name) {
+			return true
+		}
+	}
+	return false
+}
+
+func impliedHeaders(vdatRequest VdatRequest) string {
+	lines := []string{}
+	contentType := impliedContentType(vdatRequest)
+	if contentType != "" {
+		if hasHeader(vdatRequest.Headers, "Content-Type") {
+			lines = append(lines, fmt.Sprint("# Content-Type: ", contentType, " (overridden by header)"))
+		} else {
+			lines = append(lines, fmt.Sprint("Content-Type: ", contentType, " (implied by body type)"))
+		}
+	}
+	authName, authValue := impliedAuthHeader(vdatRequest)
+	if authName != "" {
+		if hasHeader(vdatRequest.Headers, authName) {
+			lines = append(lines, fmt.Sprint("# ", authName, ": ", authValue, " (overridden by header)"))
+		} else {
+			lines = append(lines, fmt.Sprint(authName, ": ", authValue, " (implied by auth)"))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func newHttpClient(vdatRequest VdatRequest, settings VdatSettings) (*http.Client, error) {
+	timeouts := settings.Timeouts.override(vdatRequest.Timeouts)
+	connectTimeout, err := parseTimeout("connect", timeouts.Connect)
+	if err != nil {
+		return nil, err
+	}
+	tlsHandshakeTimeout, err := parseTimeout("TLS handshake", timeouts.TlsHandshake)
+	if err != nil {
+		return nil, err
+	}
+	responseHeaderTimeout, err := parseTimeout("response header", timeouts.ResponseHeader)
+	if err != nil {
+		return nil, err
+	}
+	totalTimeout, err := parseTimeout("total", timeouts.Total)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsSettings, err := loadRequestTls(vdatRequest)
+	if err != nil {
+		return nil, err
+	}
+	key := transportKey{
+		tls:            tlsSettings,
+		tlsModified:    tlsFilesModified(tlsSettings),
+		insecure:       !vdatRequest.SslEnabled,
+		proxy:          settings.Proxy.override(vdatRequest.Proxy),
+		connect:        connectTimeout,
+		tlsHandshake:   tlsHandshakeTimeout,
+		responseHeader: responseHeaderTimeout,
+	}
+
+	transportCacheMutex.Lock()
+	defer transportCacheMutex.Unlock()
+	transport, found := transportCache[key]
+	if !found {
+		transport, err = newTransport(key)
+		if err != nil {
+			return nil, err
+		}
+		cacheTransport(key, transport)
+	}
+	return &http.Client{Transport: transport, Timeout: totalTimeout}, nil
+}
+
+// transports are kept per configuration so later sends reuse connections
+type transportKey struct {
+	tls            VdatTls
+	tlsModified    [3]int64
+	insecure       bool
+	proxy          VdatProxy
+	connect        time.Duration
+	tlsHandshake   time.Duration
+	responseHeader time.Duration
+}
+
+var transportCache = make(map[transportKey]*http.Transport)
+var transportOrder []transportKey
+var transportCacheMutex sync.Mutex
+
+// cacheTransport closes the transports the new one replaces: the same
+// configuration with older certificate files, and the oldest ones once the
+// cache is full. Requests still using them finish normally.
+func cacheTransport(key transportKey, transport *http.Transport) {
+	kept := transportOrder[:0]
+	for _, oldKey := range transportOrder {
+		sameSettings := oldKey
+		sameSettings.tlsModified = key.tlsModified
+		if sameSettings == key {
+			closeTransport(oldKey)
+			continue
+		}
+		kept = append(kept, oldKey)
+	}
+	for len(kept) >= MAX_CACHED_TRANSPORTS {
+		closeTransport(kept[0])
+		kept = kept[1:]
+	}
+	transportCache[key] = transport
+	transportOrder = append(kept, key)
+}
+
+func closeTransport(key transportKey) {
+	transportCache[key].CloseIdleConnections()
+	delete(transportCache, key)
+}
+
+// tlsFilesModified keys the transport on the file times too, so a replaced
+// certificate is read again
+func tlsFilesModified(settings VdatTls) [3]int64 {
+	var modified [3]int64
+	for i, path := range []string{settings.CaFile, settings.CertFile, settings.KeyFile} {
+		if path == "" {
+			continue
+		}
+		fileInfo, err := os.Stat(path)
+		if err == nil {
+			modified[i] = fileInfo.ModTime().UnixNano()
+		}
+	}
+	return modified
+}
+
+func newTransport(key transportKey) (*http.Transport, error) {
+	dialer := &net.Dialer{Timeout: key.connect}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	transport.TLSHandshakeTimeout = key.tlsHandshake
+	transport.ResponseHeaderTimeout = key.responseHeader
+	var err error
+	transport.TLSClientConfig, err = newTlsConfig(key.tls, key.insecure)
+	if err != nil {
+		return nil, err
+	}
+	transport.Proxy, err = key.proxy.proxyFunc()
+	if err != nil {
+		return nil, err
+	}
+	return transport, nil
+}
+
+func sendVdatRequest(ctx context.Context, vdatRequest VdatRequest, environment VdatEnvironment, settings VdatSettings) (VdatResponse, error) {
+	var vdatResponse VdatResponse
+
+	vdatRequest, err := applyEnvironment(vdatRequest, environment)
+	if err != nil {
+		return vdatResponse, err
+	}
+
+	client, err := newHttpClient(vdatRequest, settings)
+	if err != nil {
+		return vdatResponse, err
+	}
+
+	// fetch or refresh the oauth 2.0 token
+	if vdatRequest.Auth.Type == AUTH_TYPE_OAUTH2 {
+		token, err := getOAuth2Token(ctx, client, vdatRequest.Auth, environment.Name, openBrowser)
+		if err != nil {
+			return vdatResponse, err
+		}
+		vdatRequest.Auth.Token = token.AccessToken
+	}
+
+	req, err := buildHttpRequest(vdatRequest)
+	if err != nil {
+		return vdatResponse, err
+	}
+
+	// sign the finished request
+	err = signRequest(req, vdatRequest, time.Now())
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return vdatResponse, err
+	}
+
+	// send request
+	tracer := newTimingTracer()
+	req = req.WithContext(httptrace.WithClientTrace(ctx, tracer.clientTrace()))
+	resp, err := client.Do(req)
+	if err == nil && resp.StatusCode == http.StatusUnauthorized && vdatRequest.Auth.Type == AUTH_TYPE_DIGEST && !hasHeader(vdatRequest.Headers, "Authorization") {
+		// answer the digest challenge with a second request
+		challenge, found := findDigestChallenge(resp)
+		if found {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			resp, err = sendDigestRequest(ctx, client, tracer, vdatRequest, challenge)
+		}
+	}
+	if err != nil {
+		vdatResponse.Timing = tracer.finish()
+		vdatResponse.Elapsed = vdatResponse.Timing.Total
+		return vdatResponse, err
+	}
+	defer resp.Body.Close()
+	vdatResponse.Response = resp
+
+	// read response
+	vdatResponse.Body, err = io.ReadAll(resp.Body)
+	vdatResponse.Timing = tracer.finish()
+	vdatResponse.Elapsed = vdatResponse.Timing.Total
+	return vdatResponse, err
+}
+
+func sendDigestRequest(ctx context.Context, client *http.Client, tracer *timingTracer, vdatRequest VdatRequest, challenge map[string]string) (*http.Response, error) {
+	req, err := buildHttpRequest(vdatRequest)
+	if err != nil {
+		return nil, err
+	}
+	authorization, err := digestAuthorization(challenge, vdatRequest.Auth, req.Method, req.URL.RequestURI())
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+	req.Header.Set("Authorization", authorization)
+	req = req.WithContext(httptrace.WithClientTrace(ctx, tracer.clientTrace()))
+	return client.Do(req)
+}
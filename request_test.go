@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNewHttpClientReusesTransport(t *testing.T) {
+	useTempVdatDir(t)
+	vdatRequest := VdatRequest{Url: "https://example.com/", SslEnabled: true}
+	first, err := newHttpClient(vdatRequest, VdatSettings{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := newHttpClient(vdatRequest, VdatSettings{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Transport != second.Transport {
+		t.Error("the same configuration got a new transport")
+	}
+
+	other := vdatRequest
+	other.SslEnabled = false
+	third, err := newHttpClient(other, VdatSettings{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if third.Transport == first.Transport {
+		t.Error("an insecure request shares the transport of a verified one")
+	}
+
+	other = vdatRequest
+	other.Timeouts.Connect = "2s"
+	other.Proxy.Url = "proxy.example.com:3128"
+	fourth, err := newHttpClient(other, VdatSettings{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fourth.Transport == first.Transport {
+		t.Error("a different proxy and timeout share a transport")
+	}
+}
+
+func TestLoadRequestTls(t *testing.T) {
+	useTempVdatDir(t)
+	vdatDir, err := getVdatDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = saveHostTls("example.com", VdatTls{CaFile: "host-ca.pem", CertFile: "host.pem"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	vdatRequest := VdatRequest{
+		Url:  "https://example.com/",
+		Path: filepath.Join(vdatDir, "collection", "folder", "request.json"),
+		Tls:  VdatTls{CaFile: "certs/ca.pem"},
+	}
+	settings, err := loadRequestTls(vdatRequest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(vdatDir, "collection", "certs", "ca.pem"); settings.CaFile != want {
+		t.Errorf("request CA file = %q, want %q", settings.CaFile, want)
+	}
+	if want := filepath.Join(vdatDir, "host.pem"); settings.CertFile != want {
+		t.Errorf("host certificate = %q, want %q", settings.CertFile, want)
+	}
+
+	err = saveHostTls("example.com", VdatTls{CertPassword: "{{password}}"})
+	if err == nil {
+		t.Error("a variable was saved in the host TLS settings")
+	}
+}
+
+func TestTransportCacheDropsOldTransports(t *testing.T) {
+	useTempVdatDir(t)
+	vdatDir, err := getVdatDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	caFile := filepath.Join(vdatDir, "ca.pem")
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+	caContent := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	err = os.WriteFile(caFile, caContent, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vdatRequest := VdatRequest{Url: "https://example.com/", Tls: VdatTls{CaFile: caFile}}
+	first, err := newHttpClient(vdatRequest, VdatSettings{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	modified := time.Now().Add(time.Hour)
+	err = os.Chtimes(caFile, modified, modified)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := newHttpClient(vdatRequest, VdatSettings{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Transport == first.Transport {
+		t.Fatal("a replaced CA file kept the old transport")
+	}
+	for _, transport := range transportCache {
+		if transport == first.Transport {
+			t.Error("the transport for the old CA file is still cached")
+		}
+	}
+
+	for i := 0; i < MAX_CACHED_TRANSPORTS+4; i++ {
+		other := VdatRequest{Url: "https://example.com/"}
+		other.Timeouts.Connect = fmt.Sprint(i+1, "s")
+		_, err := newHttpClient(other, VdatSettings{})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(transportCache) > MAX_CACHED_TRANSPORTS || len(transportOrder) != len(transportCache) {
+		t.Errorf("%d transports cached, %d in order, want at most %d", len(transportCache), len(transportOrder), MAX_CACHED_TRANSPORTS)
+	}
+}
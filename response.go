@@ -0,0 +1,197 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+func formatHeader(header http.Header) string {
+	keys := []string{}
+	for key := range header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var builder strings.Builder
+	for _, key := range keys {
+		for _, value := range header[key] {
+			builder.WriteString(fmt.Sprint(key, ": ", value, "\n"))
+		}
+	}
+	return builder.String()
+}
+
+func formatResponseHeaders(resp *http.Response) string {
+	text := formatHeader(resp.Header)
+	if len(resp.Trailer) != 0 {
+		text += "\n# Trailers\n" + formatHeader(resp.Trailer)
+	}
+	return text
+}
+
+func formatResponseCookies(resp *http.Response) string {
+	var builder strings.Builder
+	for _, cookie := range resp.Cookies() {
+		builder.WriteString(fmt.Sprint(cookie.Name, "=", cookie.Value, "\n"))
+		if cookie.Domain != "" {
+			builder.WriteString(fmt.Sprint("    Domain: ", cookie.Domain, "\n"))
+		}
+		if cookie.Path != "" {
+			builder.WriteString(fmt.Sprint("    Path: ", cookie.Path, "\n"))
+		}
+		if !cookie.Expires.IsZero() {
+			builder.WriteString(fmt.Sprint("    Expires: ", cookie.Expires.UTC().Format(http.TimeFormat), "\n"))
+		}
+		if cookie.MaxAge != 0 {
+			builder.WriteString(fmt.Sprint("    Max-Age: ", cookie.MaxAge, "\n"))
+		}
+		if cookie.Secure {
+			builder.WriteString("    Secure\n")
+		}
+		if cookie.HttpOnly {
+			builder.WriteString("    HttpOnly\n")
+		}
+		switch cookie.SameSite {
+		case http.SameSiteLaxMode:
+			builder.WriteString("    SameSite: Lax\n")
+		case http.SameSiteStrictMode:
+			builder.WriteString("    SameSite: Strict\n")
+		case http.SameSiteNoneMode:
+			builder.WriteString("    SameSite: None\n")
+		}
+	}
+	return builder.String()
+}
+
+func formatResponseInfo(vdatResponse VdatResponse) string {
+	resp := vdatResponse.Response
+	contentLength := "unknown"
+	if resp.ContentLength >= 0 {
+		contentLength = fmt.Sprint(resp.ContentLength)
+	}
+
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprint("Status: ", resp.Status, "\n"))
+	builder.WriteString(fmt.Sprint("Protocol: ", resp.Proto, "\n"))
+	builder.WriteString(fmt.Sprint("Content-Length: ", contentLength, "\n"))
+	builder.WriteString(fmt.Sprint("Body size: ", len(vdatResponse.Body), " bytes\n"))
+	if resp.Uncompressed {
+		builder.WriteString("Decompressed: true\n")
+	}
+	if len(resp.TransferEncoding) != 0 {
+		builder.WriteString(fmt.Sprint("Transfer-Encoding: ", strings.Join(resp.TransferEncoding, ", "), "\n"))
+	}
+	if resp.Request != nil {
+		builder.WriteString(fmt.Sprint("Final URL: ", resp.Request.URL.String(), "\n"))
+	}
+	builder.WriteString(fmt.Sprint("Time: ", vdatResponse.Elapsed, "\n"))
+	return builder.String()
+}
+
+func formatCertificateNames(certificate *x509.Certificate) string {
+	names := []string{}
+	names = append(names, certificate.DNSNames...)
+	for _, ip := range certificate.IPAddresses {
+		names = append(names, ip.String())
+	}
+	names = append(names, certificate.EmailAddresses...)
+	for _, uri := range certificate.URIs {
+		names = append(names, uri.String())
+	}
+	return strings.Join(names, ", ")
+}
+
+func formatValidity(certificate *x509.Certificate, now time.Time) string {
+	if now.After(certificate.NotAfter) {
+		return "expired"
+	}
+	if now.Before(certificate.NotBefore) {
+		return "not yet valid"
+	}
+	return fmt.Sprint("expires in ", int(certificate.NotAfter.Sub(now).Hours()/24), " days")
+}
+
+// tlsWarnings checks the chain the server sent. With verification off these
+// problems do not fail the request, so they are the only sign of them.
+func tlsWarnings(state *tls.ConnectionState, hostname string, verified bool, now time.Time) []string {
+	warnings := []string{}
+	if !verified {
+		warnings = append(warnings, "Certificate verification is off for this request")
+	}
+	if len(state.PeerCertificates) == 0 {
+		return warnings
+	}
+	if hostname != "" {
+		err := state.PeerCertificates[0].VerifyHostname(hostname)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprint("Hostname mismatch: ", err))
+		}
+	}
+	for _, certificate := range state.PeerCertificates {
+		subject := certificate.Subject.String()
+		if now.After(certificate.NotAfter) {
+			warnings = append(warnings, fmt.Sprint("Expired on ", certificate.NotAfter.UTC().Format(time.RFC3339), ": ", subject))
+		} else if now.Before(certificate.NotBefore) {
+			warnings = append(warnings, fmt.Sprint("Not valid before ", certificate.NotBefore.UTC().Format(time.RFC3339), ": ", subject))
+		} else if certificate.NotAfter.Sub(now) < TLS_EXPIRY_WARNING {
+			warnings = append(warnings, fmt.Sprint("Expires soon (", formatValidity(certificate, now), "): ", subject))
+		}
+	}
+	return warnings
+}
+
+func formatResponseTls(resp *http.Response, verified bool, now time.Time) string {
+	state := resp.TLS
+	if state == nil {
+		return "Not a TLS connection\n"
+	}
+	hostname := state.ServerName
+	if hostname == "" && resp.Request != nil {
+		hostname = resp.Request.URL.Hostname()
+	}
+
+	var builder strings.Builder
+	for _, warning := range tlsWarnings(state, hostname, verified, now) {
+		builder.WriteString(fmt.Sprint("WARNING: ", warning, "\n"))
+	}
+	if builder.Len() != 0 {
+		builder.WriteString("\n")
+	}
+
+	alpn := state.NegotiatedProtocol
+	if alpn == "" {
+		alpn = "none"
+	}
+	builder.WriteString(fmt.Sprint("Version: ", tls.VersionName(state.Version), "\n"))
+	builder.WriteString(fmt.Sprint("Cipher suite: ", tls.CipherSuiteName(state.CipherSuite), "\n"))
+	builder.WriteString(fmt.Sprint("ALPN protocol: ", alpn, "\n"))
+	if state.ServerName != "" {
+		builder.WriteString(fmt.Sprint("Server name: ", state.ServerName, "\n"))
+	}
+	if state.DidResume {
+		builder.WriteString("Resumed: true\n")
+	}
+
+	for index, certificate := range state.PeerCertificates {
+		fingerprint := sha256.Sum256(certificate.Raw)
+		builder.WriteString(fmt.Sprint("\n# Certificate ", index+1, "\n"))
+		builder.WriteString(fmt.Sprint("Subject: ", certificate.Subject.String(), "\n"))
+		if names := formatCertificateNames(certificate); names != "" {
+			builder.WriteString(fmt.Sprint("SANs: ", names, "\n"))
+		}
+		builder.WriteString(fmt.Sprint("Issuer: ", certificate.Issuer.String(), "\n"))
+		builder.WriteString(fmt.Sprint("Serial: ", certificate.SerialNumber.Text(16), "\n"))
+		builder.WriteString(fmt.Sprint("Not before: ", certificate.NotBefore.UTC().Format(time.RFC3339), "\n"))
+		builder.WriteString(fmt.Sprint("Not after: ", certificate.NotAfter.UTC().Format(time.RFC3339), " (", formatValidity(certificate, now), ")\n"))
+		builder.WriteString(fmt.Sprint("Key: ", certificate.PublicKeyAlgorithm, ", signed with ", certificate.SignatureAlgorithm, "\n"))
+		builder.WriteString(fmt.Sprint("SHA-256 fingerprint: ", strings.ToUpper(hex.EncodeToString(fingerprint[:])), "\n"))
+	}
+	return builder.String()
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+)
+
+func runCommand(args []string, stdout io.Writer, stderr io.Writer) int {
+	flagSet := flag.NewFlagSet(RUN_COMMAND, flag.ContinueOnError)
+	flagSet.SetOutput(stderr)
+	flagSet.Usage = func() {
+		fmt.Fprintln(stderr, RUN_USAGE)
+		flagSet.PrintDefaults()
+	}
+	environmentName := flagSet.String("env", "", "name of the environment to substitute {{variables}} from")
+	settings := defaultSettings()
+	flagSet.StringVar(&settings.Timeouts.Connect, "connect-timeout", settings.Timeouts.Connect, "default connect timeout")
+	flagSet.StringVar(&settings.Timeouts.TlsHandshake, "tls-timeout", settings.Timeouts.TlsHandshake, "default TLS handshake timeout")
+	flagSet.StringVar(&settings.Timeouts.ResponseHeader, "header-timeout", settings.Timeouts.ResponseHeader, "default response header timeout")
+	flagSet.StringVar(&settings.Timeouts.Total, "timeout", settings.Timeouts.Total, "default total timeout")
+	flagSet.StringVar(&settings.Proxy.Url, "proxy", "", "default proxy url (http, https or socks5)")
+	flagSet.StringVar(&settings.Proxy.NoProxy, "noproxy", "", "comma separated hosts to reach without the proxy")
+	err := flagSet.Parse(args)
+	if err != nil {
+		return 2
+	}
+	if flagSet.NArg() == 0 {
+		flagSet.Usage()
+		return 2
+	}
+
+	err = settings.Timeouts.validate()
+	if err == nil {
+		err = settings.Proxy.validate()
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	environment, err := loadEnvironment(*environmentName)
+	if err != nil {
+		fmt.Fprintln(stderr, "Failed to load environment:", *environmentName, err)
+		return 2
+	}
+
+	var files []string
+	for _, arg := range flagSet.Args() {
+		found, err := findVdatRequestFiles(arg)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+		files = append(files, found...)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	exitCode := 0
+	for _, filename := range files {
+		if !runVdatRequestFile(ctx, filename, environment, settings, stdout, stderr) {
+			exitCode = 1
+		}
+	}
+	return exitCode
+}
+
+func resolveRunPath(path string) (string, error) {
+	_, err := os.Stat(path)
+	if err == nil || filepath.IsAbs(path) {
+		return path, err
+	}
+	vdatDir, vdatErr := getVdatDir()
+	if vdatErr != nil {
+		return path, err
+	}
+	vdatPath := filepath.Join(vdatDir, path)
+	if _, vdatErr = os.Stat(vdatPath); vdatErr != nil {
+		return path, err
+	}
+	return vdatPath, nil
+}
+
+func findVdatRequestFiles(path string) ([]string, error) {
+	path, err := resolveRunPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	err = filepath.WalkDir(path, func(filename string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if filename != path && strings.HasPrefix(entry.Name(), ".") {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !entry.IsDir() {
+			files = append(files, filename)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, errors.New(fmt.Sprint("No requests found in: ", path))
+	}
+	return files, nil
+}
+
+func runVdatRequestFile(ctx context.Context, filename string, environment VdatEnvironment, settings VdatSettings, stdout io.Writer, stderr io.Writer) bool {
+	fmt.Fprintln(stdout, "###", filename)
+
+	vdatRequest, err := loadVdatRequest(filename)
+	if err != nil {
+		fmt.Fprintln(stderr, "Failed to load file:", filename, err)
+		return false
+	}
+
+	vdatResponse, err := sendVdatRequest(ctx, vdatRequest, environment, settings)
+	if err != nil {
+		fmt.Fprintln(stderr, "Request failed:", err)
+		return false
+	}
+
+	fmt.Fprintln(stdout, vdatResponse.Response.Status)
+	fmt.Fprintln(stdout, vdatResponse.Elapsed)
+	fmt.Fprintln(stdout, smartFormat(vdatResponse.Body))
+
+	return vdatResponse.Response.StatusCode < 400
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+type VdatTimeouts struct {
+	Connect        string `json:"Connect"`
+	TlsHandshake   string `json:"TlsHandshake"`
+	ResponseHeader string `json:"ResponseHeader"`
+	Total          string `json:"Total"`
+}
+
+type VdatSettings struct {
+	Timeouts VdatTimeouts
+	Proxy    VdatProxy
+}
+
+func defaultSettings() VdatSettings {
+	return VdatSettings{
+		Timeouts: VdatTimeouts{
+			Connect:        DEFAULT_CONNECT_TIMEOUT,
+			TlsHandshake:   DEFAULT_TLS_HANDSHAKE_TIMEOUT,
+			ResponseHeader: DEFAULT_RESPONSE_HEADER_TIMEOUT,
+			Total:          DEFAULT_TOTAL_TIMEOUT,
+		},
+	}
+}
+
+func loadSettings(preferences fyne.Preferences) VdatSettings {
+	settings := defaultSettings()
+	settings.Timeouts.Connect = preferences.StringWithFallback(PREFERENCE_CONNECT_TIMEOUT, settings.Timeouts.Connect)
+	settings.Timeouts.TlsHandshake = preferences.StringWithFallback(PREFERENCE_TLS_HANDSHAKE_TIMEOUT, settings.Timeouts.TlsHandshake)
+	settings.Timeouts.ResponseHeader = preferences.StringWithFallback(PREFERENCE_RESPONSE_HEADER_TIMEOUT, settings.Timeouts.ResponseHeader)
+	settings.Timeouts.Total = preferences.StringWithFallback(PREFERENCE_TOTAL_TIMEOUT, settings.Timeouts.Total)
+	settings.Proxy.Url = preferences.String(PREFERENCE_PROXY_URL)
+	settings.Proxy.Username = preferences.String(PREFERENCE_PROXY_USERNAME)
+	settings.Proxy.Password = preferences.String(PREFERENCE_PROXY_PASSWORD)
+	settings.Proxy.NoProxy = preferences.String(PREFERENCE_NO_PROXY)
+	return settings
+}
+
+func saveSettings(preferences fyne.Preferences, settings VdatSettings) {
+	preferences.SetString(PREFERENCE_CONNECT_TIMEOUT, settings.Timeouts.Connect)
+	preferences.SetString(PREFERENCE_TLS_HANDSHAKE_TIMEOUT, settings.Timeouts.TlsHandshake)
+	preferences.SetString(PREFERENCE_RESPONSE_HEADER_TIMEOUT, settings.Timeouts.ResponseHeader)
+	preferences.SetString(PREFERENCE_TOTAL_TIMEOUT, settings.Timeouts.Total)
+	preferences.SetString(PREFERENCE_PROXY_URL, settings.Proxy.Url)
+	preferences.SetString(PREFERENCE_PROXY_USERNAME, settings.Proxy.Username)
+	preferences.SetString(PREFERENCE_PROXY_PASSWORD, settings.Proxy.Password)
+	preferences.SetString(PREFERENCE_NO_PROXY, settings.Proxy.NoProxy)
+}
+
+func parseTimeout(name string, value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout < 0 {
+		return 0, errors.New(fmt.Sprint("Invalid ", name, " timeout: ", value))
+	}
+	return timeout, nil
+}
+
+func (timeouts VdatTimeouts) validate() error {
+	for name, value := range map[string]string{
+		"connect":         timeouts.Connect,
+		"TLS handshake":   timeouts.TlsHandshake,
+		"response header": timeouts.ResponseHeader,
+		"total":           timeouts.Total,
+	} {
+		_, err := parseTimeout(name, value)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (timeouts VdatTimeouts) override(overrides VdatTimeouts) VdatTimeouts {
+	if overrides.Connect != "" {
+		timeouts.Connect = overrides.Connect
+	}
+	if overrides.TlsHandshake != "" {
+		timeouts.TlsHandshake = overrides.TlsHandshake
+	}
+	if overrides.ResponseHeader != "" {
+		timeouts.ResponseHeader = overrides.ResponseHeader
+	}
+	if overrides.Total != "" {
+		timeouts.Total = overrides.Total
+	}
+	return timeouts
+}
+
+func newTimeoutEntries(placeholders VdatTimeouts) (*widget.Form, func() VdatTimeouts, func(VdatTimeouts)) {
+	connect := widget.NewEntry()
+	connect.SetPlaceHolder(placeholders.Connect)
+	tlsHandshake := widget.NewEntry()
+	tlsHandshake.SetPlaceHolder(placeholders.TlsHandshake)
+	responseHeader := widget.NewEntry()
+	responseHeader.SetPlaceHolder(placeholders.ResponseHeader)
+	total := widget.NewEntry()
+	total.SetPlaceHolder(placeholders.Total)
+
+	form := widget.NewForm(
+		widget.NewFormItem(CONNECT_TIMEOUT_TEXT, connect),
+		widget.NewFormItem(TLS_HANDSHAKE_TIMEOUT_TEXT, tlsHandshake),
+		widget.NewFormItem(RESPONSE_HEADER_TIMEOUT_TEXT, responseHeader),
+		widget.NewFormItem(TOTAL_TIMEOUT_TEXT, total))
+	getTimeouts := func() VdatTimeouts {
+		return VdatTimeouts{
+			Connect:        connect.Text,
+			TlsHandshake:   tlsHandshake.Text,
+			ResponseHeader: responseHeader.Text,
+			Total:          total.Text,
+		}
+	}
+	setTimeouts := func(timeouts VdatTimeouts) {
+		connect.SetText(timeouts.Connect)
+		tlsHandshake.SetText(timeouts.TlsHandshake)
+		responseHeader.SetText(timeouts.ResponseHeader)
+		total.SetText(timeouts.Total)
+	}
+	return form, getTimeouts, setTimeouts
+}
+
+// settingsPopUp calls apply from the ok button, on the same path as other
+// widget callbacks, so the window settings are never set from a goroutine.
+func settingsPopUp(canvas fyne.Canvas, settings VdatSettings, apply func(VdatSettings)) {
+	timeoutsForm, getTimeouts, setTimeouts := newTimeoutEntries(VdatTimeouts{})
+	setTimeouts(settings.Timeouts)
+	proxyForm, getProxy, setProxy := newProxyEntries(VdatProxy{})
+	setProxy(settings.Proxy)
+	modalContent := container.NewVBox(
+		widget.NewLabel(SETTINGS_TIMEOUTS_TEXT), timeoutsForm,
+		widget.NewLabel(SETTINGS_PROXY_TEXT), proxyForm)
+	popUp := widget.NewModalPopUp(modalContent, canvas)
+
+	okButton := widget.NewButton(OK_BUTTON_TEXT, func() {
+		settings.Timeouts = getTimeouts()
+		settings.Proxy = getProxy()
+		err := settings.Timeouts.validate()
+		if err == nil {
+			err = settings.Proxy.validate()
+		}
+		if err != nil {
+			errorPopUp(canvas, err)
+			return
+		}
+		popUp.Hide()
+		apply(settings)
+	})
+
+	modalContent.Add(okButton)
+	popUp.Show()
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// signRequest runs once the request is fully built, so the signature covers
+// the final url, headers and body.
+func signRequest(req *http.Request, vdatRequest VdatRequest, now time.Time) error {
+	switch vdatRequest.Auth.Type {
+	case AUTH_TYPE_AWS_SIGV4:
+		if hasHeader(vdatRequest.Headers, "Authorization") {
+			return nil
+		}
+		return signAwsV4(req, vdatRequest.Auth, now)
+	case AUTH_TYPE_HMAC:
+		if hasHeader(vdatRequest.Headers, hmacHeaderName(vdatRequest.Auth)) {
+			return nil
+		}
+		return signHmac(req, vdatRequest.Auth, now)
+	}
+	return nil
+}
+
+// copyBody writes the request body to w without consuming it.
+func copyBody(req *http.Request, w io.Writer) error {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil
+	}
+	if req.GetBody == nil {
+		content, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(content))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(content)), nil
+		}
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	_, err = io.Copy(w, body)
+	return err
+}
+
+func hashBody(req *http.Request, newHash func() hash.Hash) ([]byte, error) {
+	h := newHash()
+	err := copyBody(req, h)
+	if err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+func hmacSum(newHash func() hash.Hash, key []byte, data string) []byte {
+	mac := hmac.New(newHash, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// awsEscape percent-encodes everything but the unreserved characters.
+func awsEscape(text string) string {
+	var builder strings.Builder
+	for _, b := range []byte(text) {
+		if b >= 'A' && b <= 'Z' || b >= 'a' && b <= 'z' || b >= '0' && b <= '9' || strings.IndexByte("-._~", b) >= 0 {
+			builder.WriteByte(b)
+		} else {
+			fmt.Fprintf(&builder, "%%%02X", b)
+		}
+	}
+	return builder.String()
+}
+
+func awsCanonicalPath(req *http.Request, service string) string {
+	segments := strings.Split(req.URL.EscapedPath(), "/")
+	for index, segment := range segments {
+		segment = awsEscape(decodePercent(segment))
+		// every service but s3 encodes the path twice
+		if service != "s3" {
+			segment = awsEscape(segment)
+		}
+		segments[index] = segment
+	}
+	path := strings.Join(segments, "/")
+	if path == "" {
+		path = "/"
+	}
+	return path
+}
+
+// awsCanonicalQuery sorts the escaped pairs by name, then by value. Sorting
+// the joined "name=value" text would put "a1=x" before "a=y".
+func awsCanonicalQuery(rawQuery string) string {
+	pairs := []KeyValue{}
+	for _, pair := range parseQueryPairs(rawQuery) {
+		key, err := url.QueryUnescape(pair.Key)
+		if err != nil {
+			key = pair.Key
+		}
+		value, err := url.QueryUnescape(pair.Value)
+		if err != nil {
+			value = pair.Value
+		}
+		pairs = append(pairs, KeyValue{Key: awsEscape(key), Value: awsEscape(value)})
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].Key != pairs[j].Key {
+			return pairs[i].Key < pairs[j].Key
+		}
+		return pairs[i].Value < pairs[j].Value
+	})
+	encoded := []string{}
+	for _, pair := range pairs {
+		encoded = append(encoded, pair.Key+"="+pair.Value)
+	}
+	return strings.Join(encoded, "&")
+}
+
+func signAwsV4(req *http.Request, auth VdatAuth, now time.Time) error {
+	if auth.AccessKey == "" || auth.SecretKey == "" || auth.Region == "" || auth.Service == "" {
+		return errors.New("AWS Signature V4 needs a region, service, access key and secret key")
+	}
+	payloadHash, err := hashBody(req, sha256.New)
+	if err != nil {
+		return err
+	}
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := now.UTC().Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	if auth.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", auth.SessionToken)
+	}
+	if auth.Service == "s3" {
+		req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(payloadHash))
+	}
+
+	// sign the host, the content type and every x-amz- header
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	for name, values := range req.Header {
+		name = strings.ToLower(name)
+		if name == "content-type" || strings.HasPrefix(name, "x-amz-") {
+			trimmed := []string{}
+			for _, value := range values {
+				trimmed = append(trimmed, strings.Join(strings.Fields(value), " "))
+			}
+			headers[name] = strings.Join(trimmed, ",")
+		}
+	}
+	names := []string{}
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		awsCanonicalPath(req, auth.Service),
+		awsCanonicalQuery(req.URL.RawQuery),
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash),
+	}, "\n")
+	canonicalHash := sha256.Sum256([]byte(canonicalRequest))
+	scope := strings.Join([]string{date, auth.Region, auth.Service, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, hex.EncodeToString(canonicalHash[:])}, "\n")
+
+	key := hmacSum(sha256.New, []byte("AWS4"+auth.SecretKey), date)
+	key = hmacSum(sha256.New, key, auth.Region)
+	key = hmacSum(sha256.New, key, auth.Service)
+	key = hmacSum(sha256.New, key, "aws4_request")
+	signature := hex.EncodeToString(hmacSum(sha256.New, key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprint("AWS4-HMAC-SHA256 Credential=", auth.AccessKey, "/", scope, ", SignedHeaders=", signedHeaders, ", Signature=", signature))
+	return nil
+}
+
+func hmacHeaderName(auth VdatAuth) string {
+	name := strings.TrimSpace(auth.HmacHeader)
+	if name == "" {
+		return HMAC_DEFAULT_HEADER
+	}
+	return name
+}
+
+func hmacHash(algorithm string) (func() hash.Hash, error) {
+	switch algorithm {
+	case HMAC_ALGORITHM_SHA1:
+		return sha1.New, nil
+	case HMAC_ALGORITHM_SHA256, "":
+		return sha256.New, nil
+	case HMAC_ALGORITHM_SHA512:
+		return sha512.New, nil
+	}
+	return nil, errors.New(fmt.Sprint("Unsupported HMAC algorithm: ", algorithm))
+}
+
+// signHmac signs either the body alone or a canonical string made of the
+// method, request uri, Date header and body hash, one per line.
+func signHmac(req *http.Request, auth VdatAuth, now time.Time) error {
+	if auth.HmacKey == "" {
+		return errors.New("HMAC signing needs a key")
+	}
+	name := hmacHeaderName(auth)
+	if !validHeaderName(name) {
+		return errors.New(fmt.Sprint("Invalid HMAC header name: ", name))
+	}
+	newHash, err := hmacHash(auth.HmacAlgorithm)
+	if err != nil {
+		return err
+	}
+
+	mac := hmac.New(newHash, []byte(auth.HmacKey))
+	if auth.HmacSigns == HMAC_SIGNS_REQUEST {
+		if req.Header.Get("Date") == "" {
+			req.Header.Set("Date", now.UTC().Format(http.TimeFormat))
+		}
+		bodyHash, err := hashBody(req, newHash)
+		if err != nil {
+			return err
+		}
+		mac.Write([]byte(strings.Join([]string{req.Method, req.URL.RequestURI(), req.Header.Get("Date"), hex.EncodeToString(bodyHash)}, "\n")))
+	} else {
+		err = copyBody(req, mac)
+		if err != nil {
+			return err
+		}
+	}
+
+	signature := mac.Sum(nil)
+	if auth.HmacEncoding == HMAC_ENCODING_BASE64 {
+		req.Header.Set(name, base64.StdEncoding.EncodeToString(signature))
+	} else {
+		req.Header.Set(name, hex.EncodeToString(signature))
+	}
+	return nil
+}
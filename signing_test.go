@@ -0,0 +1,65 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAwsCanonicalQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"", ""},
+		{"max-keys=2&max=1&a1=x&a=y", "a=y&a1=x&max=1&max-keys=2"},
+		{"Param2=value2&Param1=value1", "Param1=value1&Param2=value2"},
+		{"Param1=value2&Param1=Value1", "Param1=Value1&Param1=value2"},
+		{"q=a+b&p=%7E", "p=~&q=a%20b"},
+	}
+	for _, test := range tests {
+		got := awsCanonicalQuery(test.query)
+		if got != test.want {
+			t.Errorf("awsCanonicalQuery(%q) = %q, want %q", test.query, got, test.want)
+		}
+	}
+}
+
+// vectors from the AWS Signature Version 4 test suite
+func TestSignAwsV4TestSuite(t *testing.T) {
+	auth := VdatAuth{
+		Type:      AUTH_TYPE_AWS_SIGV4,
+		AccessKey: "AKIDEXAMPLE",
+		SecretKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+		Region:    "us-east-1",
+		Service:   "service",
+	}
+	now := time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		url       string
+		signature string
+	}{
+		{"get-vanilla", "https://example.amazonaws.com/", "5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"},
+		{"get-vanilla-query-order-key-case", "https://example.amazonaws.com/?Param2=value2&Param1=value1", "b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500"},
+	}
+	for _, test := range tests {
+		req, err := http.NewRequest(http.MethodGet, test.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = signAwsV4(req, auth, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=" + test.signature
+		got := req.Header.Get("Authorization")
+		if got != want {
+			t.Errorf("%s: Authorization = %q, want %q", test.name, got, want)
+		}
+		if !strings.HasPrefix(req.Header.Get("X-Amz-Date"), "20150830T123600Z") {
+			t.Errorf("%s: X-Amz-Date = %q", test.name, req.Header.Get("X-Amz-Date"))
+		}
+	}
+}
@@ -0,0 +1,343 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+type VdatTls struct {
+	CaFile       string `json:"CaFile"`
+	CertFile     string `json:"CertFile"`
+	KeyFile      string `json:"KeyFile"`
+	CertPassword string `json:"CertPassword"`
+	ServerName   string `json:"ServerName"`
+	MinVersion   string `json:"MinVersion"`
+	MaxVersion   string `json:"MaxVersion"`
+}
+
+// override keeps the client certificate, key and password together so a
+// request never mixes its certificate with the key saved for the host.
+func (settings VdatTls) override(overrides VdatTls) VdatTls {
+	if overrides.CaFile != "" {
+		settings.CaFile = overrides.CaFile
+	}
+	if overrides.CertFile != "" {
+		settings.CertFile = overrides.CertFile
+		settings.KeyFile = overrides.KeyFile
+		settings.CertPassword = overrides.CertPassword
+	}
+	if overrides.ServerName != "" {
+		settings.ServerName = overrides.ServerName
+	}
+	if overrides.MinVersion != "" {
+		settings.MinVersion = overrides.MinVersion
+	}
+	if overrides.MaxVersion != "" {
+		settings.MaxVersion = overrides.MaxVersion
+	}
+	return settings
+}
+
+// resolvePaths makes the file paths absolute, starting from the collection
+// folder of the request they were saved with.
+func (settings VdatTls) resolvePaths(requestPath string) (VdatTls, error) {
+	for _, path := range []*string{&settings.CaFile, &settings.CertFile, &settings.KeyFile} {
+		if *path == "" {
+			continue
+		}
+		resolved, err := resolveBodyFilePath(*path, requestPath)
+		if err != nil {
+			return settings, err
+		}
+		*path = resolved
+	}
+	return settings, nil
+}
+
+// loadRequestTls merges the settings saved for the host of the request with
+// its own. Host files start at the vdat folder, request files at the
+// collection folder of the request.
+func loadRequestTls(vdatRequest VdatRequest) (VdatTls, error) {
+	hostTls, err := loadHostTls(urlHostname(vdatRequest.Url))
+	if err != nil {
+		return VdatTls{}, err
+	}
+	hostTls, err = hostTls.resolvePaths("")
+	if err != nil {
+		return VdatTls{}, err
+	}
+	requestTls, err := vdatRequest.Tls.resolvePaths(vdatRequest.Path)
+	if err != nil {
+		return VdatTls{}, err
+	}
+	return hostTls.override(requestTls), nil
+}
+
+func urlHostname(urlText string) string {
+	parsedUrl, err := url.Parse(urlText)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(parsedUrl.Hostname())
+}
+
+func getHostTlsPath(host string) (string, error) {
+	if host == "" || host != filepath.Base(host) || strings.HasPrefix(host, ".") {
+		return "", errors.New(fmt.Sprint("Invalid host: ", host))
+	}
+	vdatDir, err := getVdatDir()
+	if err != nil {
+		return "", err
+	}
+	tlsDir := filepath.Join(vdatDir, TLS_DIR)
+	err = os.MkdirAll(tlsDir, os.ModePerm)
+	return filepath.Join(tlsDir, host), err
+}
+
+func loadHostTls(host string) (VdatTls, error) {
+	if host == "" {
+		return VdatTls{}, nil
+	}
+	hostTlsPath, err := getHostTlsPath(host)
+	if err != nil {
+		return VdatTls{}, err
+	}
+	file, err := os.Open(hostTlsPath)
+	if os.IsNotExist(err) {
+		return VdatTls{}, nil
+	}
+	if err != nil {
+		return VdatTls{}, err
+	}
+	defer file.Close()
+
+	settings := VdatTls{}
+	decoder := json.NewDecoder(file)
+	err = decoder.Decode(&settings)
+	return settings, err
+}
+
+// saveHostTls refuses variables, as host settings are merged in after the
+// environment is applied.
+func saveHostTls(host string, settings VdatTls) error {
+	for _, value := range []string{settings.CaFile, settings.CertFile, settings.KeyFile, settings.CertPassword, settings.ServerName} {
+		if variablePattern.MatchString(value) {
+			return errors.New(fmt.Sprint("Variables are not supported in TLS settings saved for a host: ", value))
+		}
+	}
+	hostTlsPath, err := getHostTlsPath(host)
+	if err != nil {
+		return err
+	}
+	if settings == (VdatTls{}) {
+		err = os.Remove(hostTlsPath)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	file, err := os.OpenFile(hostTlsPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	return encoder.Encode(settings)
+}
+
+func parseTlsVersion(version string) (uint16, error) {
+	switch version {
+	case "", TLS_VERSION_DEFAULT:
+		return 0, nil
+	case "1.0":
+		return tls.VersionTLS10, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	}
+	return 0, errors.New(fmt.Sprint("Invalid TLS version: ", version))
+}
+
+func readTlsFile(name string, path string) ([]byte, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.New(fmt.Sprint("Error reading ", name, ": ", err))
+	}
+	return content, nil
+}
+
+// loadClientCertificate reads a PEM certificate and key, a PEM file holding
+// both, or a PKCS#12 bundle.
+func loadClientCertificate(settings VdatTls) (tls.Certificate, error) {
+	certContent, err := readTlsFile("client certificate", settings.CertFile)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	extension := strings.ToLower(filepath.Ext(settings.CertFile))
+	if extension == ".p12" || extension == ".pfx" {
+		key, leaf, chain, err := pkcs12.DecodeChain(certContent, settings.CertPassword)
+		if err != nil {
+			return tls.Certificate{}, errors.New(fmt.Sprint("Error reading client certificate: ", err))
+		}
+		certificate := tls.Certificate{Certificate: [][]byte{leaf.Raw}, PrivateKey: key, Leaf: leaf}
+		for _, ca := range chain {
+			certificate.Certificate = append(certificate.Certificate, ca.Raw)
+		}
+		return certificate, nil
+	}
+
+	keyContent := certContent
+	if settings.KeyFile != "" {
+		keyContent, err = readTlsFile("client key", settings.KeyFile)
+		if err != nil {
+			return tls.Certificate{}, err
+		}
+	}
+	certificate, err := tls.X509KeyPair(certContent, keyContent)
+	if err != nil {
+		return tls.Certificate{}, errors.New(fmt.Sprint("Error reading client certificate: ", err))
+	}
+	return certificate, nil
+}
+
+// newTlsConfig expects the paths resolved by loadRequestTls
+func newTlsConfig(settings VdatTls, insecure bool) (*tls.Config, error) {
+	config := &tls.Config{
+		InsecureSkipVerify: insecure,
+		ServerName:         settings.ServerName,
+	}
+
+	var err error
+	config.MinVersion, err = parseTlsVersion(settings.MinVersion)
+	if err != nil {
+		return nil, err
+	}
+	config.MaxVersion, err = parseTlsVersion(settings.MaxVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	// trust the bundle on top of the system roots
+	if settings.CaFile != "" {
+		caContent, err := readTlsFile("CA bundle", settings.CaFile)
+		if err != nil {
+			return nil, err
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(caContent) {
+			return nil, errors.New(fmt.Sprint("No certificates found in CA bundle: ", settings.CaFile))
+		}
+		config.RootCAs = pool
+	}
+
+	if settings.CertFile != "" {
+		certificate, err := loadClientCertificate(settings)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{certificate}
+	}
+	return config, nil
+}
+
+func newTlsEntries(window fyne.Window, requestPath func() string) (fyne.CanvasObject, func() VdatTls, func(VdatTls), func(VdatTls)) {
+	caFile := widget.NewEntry()
+	certFile := widget.NewEntry()
+	keyFile := widget.NewEntry()
+	certPassword := widget.NewPasswordEntry()
+	serverName := widget.NewEntry()
+	minVersion := widget.NewSelect(TLS_VERSIONS, nil)
+	maxVersion := widget.NewSelect(TLS_VERSIONS, nil)
+
+	form := widget.NewForm(
+		widget.NewFormItem(CA_FILE_TEXT, newFileBrowseControls(window, caFile, requestPath)),
+		widget.NewFormItem(CERT_FILE_TEXT, newFileBrowseControls(window, certFile, requestPath)),
+		widget.NewFormItem(KEY_FILE_TEXT, newFileBrowseControls(window, keyFile, requestPath)),
+		widget.NewFormItem(CERT_PASSWORD_TEXT, certPassword),
+		widget.NewFormItem(SERVER_NAME_TEXT, serverName),
+		widget.NewFormItem(MIN_TLS_VERSION_TEXT, minVersion),
+		widget.NewFormItem(MAX_TLS_VERSION_TEXT, maxVersion))
+	getTls := func() VdatTls {
+		settings := VdatTls{
+			CaFile:       caFile.Text,
+			CertFile:     certFile.Text,
+			KeyFile:      keyFile.Text,
+			CertPassword: certPassword.Text,
+			ServerName:   serverName.Text,
+			MinVersion:   minVersion.Selected,
+			MaxVersion:   maxVersion.Selected,
+		}
+		if settings.MinVersion == TLS_VERSION_DEFAULT {
+			settings.MinVersion = ""
+		}
+		if settings.MaxVersion == TLS_VERSION_DEFAULT {
+			settings.MaxVersion = ""
+		}
+		return settings
+	}
+	setTls := func(settings VdatTls) {
+		caFile.SetText(settings.CaFile)
+		certFile.SetText(settings.CertFile)
+		keyFile.SetText(settings.KeyFile)
+		certPassword.SetText(settings.CertPassword)
+		serverName.SetText(settings.ServerName)
+		if settings.MinVersion == "" {
+			settings.MinVersion = TLS_VERSION_DEFAULT
+		}
+		minVersion.SetSelected(settings.MinVersion)
+		if settings.MaxVersion == "" {
+			settings.MaxVersion = TLS_VERSION_DEFAULT
+		}
+		maxVersion.SetSelected(settings.MaxVersion)
+	}
+	setPlaceholders := func(settings VdatTls) {
+		caFile.SetPlaceHolder(settings.CaFile)
+		certFile.SetPlaceHolder(settings.CertFile)
+		keyFile.SetPlaceHolder(settings.KeyFile)
+		serverName.SetPlaceHolder(settings.ServerName)
+	}
+	setTls(VdatTls{})
+	return form, getTls, setTls, setPlaceholders
+}
+
+func tlsHostPopUp(canvas fyne.Canvas, window fyne.Window, host string) {
+	settings, err := loadHostTls(host)
+	if err != nil {
+		errorPopUp(canvas, err)
+		return
+	}
+	tlsForm, getTls, setTls, _ := newTlsEntries(window, nil)
+	setTls(settings)
+	modalContent := container.NewVBox(widget.NewLabel(fmt.Sprint(TLS_HOST_TEXT, host)), tlsForm)
+	popUp := widget.NewModalPopUp(modalContent, canvas)
+	okButton := widget.NewButton(OK_BUTTON_TEXT, func() {
+		err := saveHostTls(host, getTls())
+		if err != nil {
+			errorPopUp(canvas, err)
+			return
+		}
+		popUp.Hide()
+	})
+	cancelButton := widget.NewButton(CANCEL_BUTTON_TEXT, func() { popUp.Hide() })
+	modalContent.Add(container.NewHBox(okButton, cancelButton))
+	popUp.Show()
+}
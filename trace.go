@@ -0,0 +1,135 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"time"
+)
+
+type VdatTiming struct {
+	DnsLookup         time.Duration
+	TcpConnect        time.Duration
+	TlsHandshake      time.Duration
+	FirstByte         time.Duration
+	Download          time.Duration
+	Total             time.Duration
+	Connections       int
+	ConnectionReused  bool
+	ConnectionWasIdle bool
+	ConnectionIdle    time.Duration
+	RemoteAddr        string
+}
+
+type timingTracer struct {
+	mutex        sync.Mutex
+	start        time.Time
+	dnsStart     time.Time
+	connectStart time.Time
+	tlsStart     time.Time
+	wroteRequest time.Time
+	firstByte    time.Time
+	timing       VdatTiming
+}
+
+func newTimingTracer() *timingTracer {
+	return &timingTracer{start: time.Now()}
+}
+
+func (tracer *timingTracer) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			tracer.mutex.Lock()
+			defer tracer.mutex.Unlock()
+			tracer.dnsStart = time.Now()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			tracer.mutex.Lock()
+			defer tracer.mutex.Unlock()
+			tracer.timing.DnsLookup += time.Since(tracer.dnsStart)
+		},
+		ConnectStart: func(string, string) {
+			tracer.mutex.Lock()
+			defer tracer.mutex.Unlock()
+			// dual-stack dialing may start several attempts, time from the first
+			if tracer.connectStart.IsZero() {
+				tracer.connectStart = time.Now()
+			}
+		},
+		ConnectDone: func(_ string, _ string, err error) {
+			tracer.mutex.Lock()
+			defer tracer.mutex.Unlock()
+			if err == nil && !tracer.connectStart.IsZero() {
+				tracer.timing.TcpConnect += time.Since(tracer.connectStart)
+				tracer.connectStart = time.Time{}
+			}
+		},
+		TLSHandshakeStart: func() {
+			tracer.mutex.Lock()
+			defer tracer.mutex.Unlock()
+			tracer.tlsStart = time.Now()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			tracer.mutex.Lock()
+			defer tracer.mutex.Unlock()
+			tracer.timing.TlsHandshake += time.Since(tracer.tlsStart)
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			tracer.mutex.Lock()
+			defer tracer.mutex.Unlock()
+			tracer.timing.Connections++
+			tracer.timing.ConnectionReused = info.Reused
+			tracer.timing.ConnectionWasIdle = info.WasIdle
+			tracer.timing.ConnectionIdle = info.IdleTime
+			if info.Conn != nil {
+				tracer.timing.RemoteAddr = info.Conn.RemoteAddr().String()
+			}
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			tracer.mutex.Lock()
+			defer tracer.mutex.Unlock()
+			tracer.wroteRequest = time.Now()
+		},
+		GotFirstResponseByte: func() {
+			tracer.mutex.Lock()
+			defer tracer.mutex.Unlock()
+			tracer.firstByte = time.Now()
+			if !tracer.wroteRequest.IsZero() {
+				tracer.timing.FirstByte = tracer.firstByte.Sub(tracer.wroteRequest)
+			}
+		},
+	}
+}
+
+func (tracer *timingTracer) finish() VdatTiming {
+	tracer.mutex.Lock()
+	defer tracer.mutex.Unlock()
+	end := time.Now()
+	if !tracer.firstByte.IsZero() {
+		tracer.timing.Download = end.Sub(tracer.firstByte)
+	}
+	tracer.timing.Total = end.Sub(tracer.start)
+	return tracer.timing
+}
+
+func formatTiming(timing VdatTiming) string {
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprint("DNS lookup: ", timing.DnsLookup, "\n"))
+	builder.WriteString(fmt.Sprint("TCP connect: ", timing.TcpConnect, "\n"))
+	builder.WriteString(fmt.Sprint("TLS handshake: ", timing.TlsHandshake, "\n"))
+	builder.WriteString(fmt.Sprint("Time to first byte: ", timing.FirstByte, "\n"))
+	builder.WriteString(fmt.Sprint("Download: ", timing.Download, "\n"))
+	builder.WriteString(fmt.Sprint("Total: ", timing.Total, "\n"))
+	builder.WriteString("\n")
+	if timing.RemoteAddr != "" {
+		builder.WriteString(fmt.Sprint("Remote address: ", timing.RemoteAddr, "\n"))
+	}
+	builder.WriteString(fmt.Sprint("Connections used: ", timing.Connections, "\n"))
+	builder.WriteString(fmt.Sprint("Connection reused: ", timing.ConnectionReused, "\n"))
+	if timing.ConnectionWasIdle {
+		builder.WriteString(fmt.Sprint("Connection idle for: ", timing.ConnectionIdle, "\n"))
+	}
+	return builder.String()
+}